@@ -0,0 +1,138 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// expandAliases replaces every unquoted word in command position that names
+// an alias. Words of the replacement are expanded in turn, but an alias is
+// never expanded again inside its own replacement.
+func (shell *Shell) expandAliases(line string) string {
+	if len(shell.aliases) == 0 {
+		return line
+	}
+
+	var builder strings.Builder
+	lexar := newLexar(line)
+	last := 0
+	commandPosition := true
+
+	for token := lexar.nextToken(); token.tokenType != EOF; token = lexar.nextToken() {
+		switch token.tokenType {
+		case PIPE, LIST:
+			commandPosition = true
+		case STRING:
+			if commandPosition && line[token.pos:lexar.i] == token.literal {
+				if value, ok := shell.aliasValue(token.literal, map[string]bool{}); ok {
+					builder.WriteString(line[last:token.pos])
+					builder.WriteString(value)
+					last = lexar.i
+				}
+			}
+			commandPosition = token.literal == "{"
+		case REDIRECT:
+			commandPosition = false
+		}
+	}
+	builder.WriteString(line[last:])
+
+	return builder.String()
+}
+
+func (shell *Shell) aliasValue(name string, seen map[string]bool) (string, bool) {
+	value, ok := shell.aliases[name]
+	if !ok || seen[name] {
+		return "", false
+	}
+	seen[name] = true
+
+	value = strings.TrimLeft(value, " ")
+	first := value
+	if index := strings.IndexByte(value, ' '); index >= 0 {
+		first = value[:index]
+	}
+	if expanded, ok := shell.aliasValue(first, seen); ok {
+		return expanded + value[len(first):], true
+	}
+
+	return value, true
+}
+
+// singleQuote quotes value for the parser, a single quote inside closes the
+// quoting, is escaped and opens it again.
+func singleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func formatAlias(name string, value string) string {
+	return "alias " + name + "=" + singleQuote(value)
+}
+
+func (shell *Shell) handleAliasCommand(args []string) (string, error) {
+	if len(args) == 0 {
+		names := make([]string, 0, len(shell.aliases))
+		for name := range shell.aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		lines := []string{}
+		for _, name := range names {
+			lines = append(lines, formatAlias(name, shell.aliases[name]))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	lines := []string{}
+	missing := []string{}
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			if value, ok := shell.aliases[name]; ok {
+				lines = append(lines, formatAlias(name, value))
+			} else {
+				missing = append(missing, "alias: "+name+": not found")
+			}
+			continue
+		}
+		if name == "" || strings.ContainsAny(name, "/$`'\"\\ ") {
+			missing = append(missing, "alias: `"+arg+"': invalid alias name")
+			continue
+		}
+		if shell.aliases == nil {
+			shell.aliases = map[string]string{}
+		}
+		shell.aliases[name] = value
+	}
+
+	if len(missing) > 0 {
+		return strings.Join(lines, "\n"), fmt.Errorf("%s", strings.Join(missing, "\n"))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (shell *Shell) handleUnaliasCommand(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", &statusError{status: 2, message: "unalias: usage: unalias [-a] name [name ...]"}
+	}
+	if args[0] == "-a" {
+		shell.aliases = nil
+		return "", nil
+	}
+
+	missing := []string{}
+	for _, name := range args {
+		if _, ok := shell.aliases[name]; !ok {
+			missing = append(missing, "unalias: "+name+": not found")
+			continue
+		}
+		delete(shell.aliases, name)
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(missing, "\n"))
+	}
+	return "", nil
+}
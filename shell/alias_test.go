@@ -0,0 +1,50 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAliasExpansion(t *testing.T) {
+	cases := []Case{
+		{input: "alias say='echo said'\nsay hello", output: "said hello", name: "first word"},
+		{input: "alias say='echo said'\necho say", output: "say", name: "only command position"},
+		{input: "alias say='echo said'\n'say' hello", err: "say: command not found", name: "quoted word is not expanded"},
+		{input: "alias say='echo said'\nsay a | cat; say b && say c", output: "said a\nsaid b\nsaid c", name: "after pipe and list operators"},
+		{input: "alias echo='echo loud'\necho x", output: "loud x", name: "no recursion into itself"},
+		{input: "alias one=two\nalias two='echo deep'\none", output: "deep", name: "nested aliases"},
+		{input: "alias b='echo b' a='echo a'\nalias", output: "alias a='echo a'\nalias b='echo b'", name: "list aliases"},
+		{input: "alias a='echo a'\nunalias a\nalias a", err: "alias: a: not found", name: "unalias"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"PATH=/usr/bin:/bin"}})
+
+			for _, line := range strings.Split(testCase.input, "\n") {
+				shell.Eval(line)
+			}
+
+			if got := strings.TrimRight(output.String(), "\n"); got != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, got)
+			}
+			if got := strings.TrimRight(errout.String(), "\n"); got != testCase.err {
+				t.Errorf("Expected error to be %q, got: %q", testCase.err, got)
+			}
+		})
+	}
+}
+
+func TestExpandAliases(t *testing.T) {
+	shell := New(Options{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}, Env: []string{}})
+	shell.aliases = map[string]string{"ll": "ls -l", "say": "echo said"}
+
+	line := "ll x | say 'll' && ll>out; f() { say; } "
+	expected := "ls -l x | echo said 'll' && ls -l>out; f() { echo said; } "
+	if got := shell.expandAliases(line); got != expected {
+		t.Errorf("Expected %q, got: %q", expected, got)
+	}
+}
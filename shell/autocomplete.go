@@ -0,0 +1,493 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+)
+
+// completionQueryItems is how many candidates are listed before asking
+// whether to display them all, as readline's completion-query-items.
+const completionQueryItems = 100
+
+type AutoComplete struct {
+	shell      *Shell
+	tabCount   int
+	lastPrefix string
+
+	// readline is the line editor the completer belongs to, the bell, the
+	// question and the list of candidates are written through it so the line
+	// being edited is drawn again below them.
+	readline *readline.Instance
+}
+
+// findCommonPrefix is the longest prefix all of args share, a single item is
+// its own prefix.
+func findCommonPrefix(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	prefix := args[0]
+	for _, item := range args[1:] {
+		length := 0
+		for length < len(prefix) && length < len(item) && prefix[length] == item[length] {
+			length++
+		}
+		prefix = prefix[:length]
+	}
+	// do not cut a character in half
+	for len(prefix) > 0 && !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
+
+// formatColumns lays items out in columns filled top to bottom, as many as
+// fit in width, every line ending with a newline.
+func formatColumns(items []string, width int) string {
+	if len(items) == 0 {
+		return ""
+	}
+	columnWidth := 0
+	for _, item := range items {
+		columnWidth = max(columnWidth, utf8.RuneCountInString(item)+2)
+	}
+	columns := max(1, width/columnWidth)
+	rows := (len(items) + columns - 1) / columns
+
+	var builder strings.Builder
+	for row := 0; row < rows; row++ {
+		line := ""
+		for column := 0; column < columns; column++ {
+			index := column*rows + row
+			if index >= len(items) {
+				break
+			}
+			item := items[index]
+			if column < columns-1 && index+rows < len(items) {
+				item += strings.Repeat(" ", columnWidth-utf8.RuneCountInString(item))
+			}
+			line += item
+		}
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
+
+// registry is the registry of the shell, or the default builtins when the
+// completer has no shell.
+func (a *AutoComplete) registry() *Registry {
+	if a.shell == nil {
+		return NewRegistry()
+	}
+	return a.shell.Builtins()
+}
+
+// matcher is the completionMatcher of the shell, a completer without one
+// matches strictly.
+func (a *AutoComplete) matcher() completionMatcher {
+	if a.shell == nil {
+		return completionMatcher{}
+	}
+	return a.shell.completionMatcher()
+}
+
+// commandCandidates are the builtins, functions, aliases and PATH
+// executables that start with prefix, each followed by the space that ends a
+// completed word.
+func (a *AutoComplete) commandCandidates(prefix string) []string {
+	path := os.Getenv("PATH")
+	index := &executableIndex{}
+	if a.shell != nil {
+		path = a.shell.searchPath()
+		index = &a.shell.executables
+	}
+
+	matcher := a.matcher()
+	var names []string
+	if matcher.strict() {
+		names = index.withPrefix(path, prefix)
+	} else {
+		names = slices.Clone(index.current(path))
+	}
+	for _, name := range a.registry().Names() {
+		names = append(names, string(name))
+	}
+	if a.shell != nil {
+		for name := range a.shell.functions {
+			names = append(names, name)
+		}
+		for name := range a.shell.aliases {
+			names = append(names, name)
+		}
+	}
+
+	candidates := []string{}
+	for _, name := range names {
+		if matcher.matches(name, prefix) {
+			candidates = append(candidates, name+" ")
+		}
+	}
+	sort.Strings(candidates)
+	return slices.Compact(candidates)
+}
+
+// pathCandidates lists the directory of word, relative to the working
+// directory of the shell or to the home directory after a ~ or ~user. Directories end with a slash
+// so completion can go on inside them, files with a space, and files are
+// left out when onlyDirectories is set.
+func (a *AutoComplete) pathCandidates(word string, onlyDirectories bool) []string {
+	shell := a.shell
+	if shell == nil {
+		shell = &Shell{}
+	}
+
+	if word == "~" {
+		return []string{"~/"}
+	}
+
+	dir, base := "", word
+	if index := strings.LastIndex(word, "/"); index >= 0 {
+		dir, base = word[:index+1], word[index+1:]
+	}
+
+	directory := dir
+	if strings.HasPrefix(dir, "~") {
+		user, rest, _ := strings.Cut(dir[1:], "/")
+		home, err := shell.tildeDirectory(user)
+		if err != nil {
+			return nil
+		}
+		directory = filepath.Join(home, rest)
+	}
+	if directory == "" {
+		directory = "."
+	}
+
+	entries, err := os.ReadDir(shell.resolvePath(directory))
+	if err != nil {
+		return nil
+	}
+
+	matcher := a.matcher()
+	candidates := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !matcher.matches(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
+		}
+		// a symlink to a directory completes like the directory
+		info, err := os.Stat(filepath.Join(shell.resolvePath(directory), name))
+		if err == nil && info.IsDir() {
+			candidates = append(candidates, dir+name+"/")
+		} else if !onlyDirectories {
+			candidates = append(candidates, dir+name+" ")
+		}
+	}
+	return candidates
+}
+
+// variableCandidates completes the name after the last $ or ${ of word,
+// ok is false when word does not end in one. A name whose value is a
+// directory ends with a slash, like a directory.
+func (a *AutoComplete) variableCandidates(word string) (candidates []string, ok bool) {
+	index := strings.LastIndex(word, "$")
+	if index < 0 {
+		return nil, false
+	}
+	before, prefix := word[:index+1], word[index+1:]
+	brace := strings.HasPrefix(prefix, "{")
+	if brace {
+		before, prefix = before+"{", prefix[1:]
+	}
+	if prefix != "" {
+		if _, _, isName := assignment(prefix + "="); !isName {
+			return nil, false
+		}
+	}
+
+	shell := a.shell
+	if shell == nil {
+		shell = &Shell{}
+	}
+	matcher := a.matcher()
+	candidates = []string{}
+	for _, item := range shell.environ() {
+		name, value, _ := strings.Cut(item, "=")
+		if !matcher.matches(name, prefix) {
+			continue
+		}
+		if brace {
+			name += "}"
+		}
+		if info, err := os.Stat(shell.resolvePath(value)); value != "" && err == nil && info.IsDir() {
+			candidates = append(candidates, before+name+"/")
+		} else {
+			candidates = append(candidates, before+name+" ")
+		}
+	}
+	sort.Strings(candidates)
+	return candidates, true
+}
+
+// userCandidates completes ~name with the users in /etc/passwd, each
+// followed by the slash of their home directory.
+func (a *AutoComplete) userCandidates(word string) []string {
+	shell := a.shell
+	if shell == nil {
+		shell = &Shell{}
+	}
+	return a.nameCandidates("~", shell.userNames.read("/etc/passwd", passwdUsers), word[1:], "/")
+}
+
+// hostCandidates completes the host name after the last @ of word, as in
+// ssh user@host, with the names in /etc/hosts.
+func (a *AutoComplete) hostCandidates(word string) []string {
+	index := strings.LastIndex(word, "@")
+	if index < 0 || strings.ContainsAny(word[index:], "/:") {
+		return nil
+	}
+	shell := a.shell
+	if shell == nil {
+		shell = &Shell{}
+	}
+	return a.nameCandidates(word[:index+1], shell.hostNames.read("/etc/hosts", hostsNames), word[index+1:], " ")
+}
+
+// nameCandidates are the names that match prefix, each put after before and
+// followed by end.
+func (a *AutoComplete) nameCandidates(before string, names []string, prefix string, end string) []string {
+	matcher := a.matcher()
+	candidates := []string{}
+	for _, name := range names {
+		if matcher.matches(name, prefix) {
+			candidates = append(candidates, before+name+end)
+		}
+	}
+	sort.Strings(candidates)
+	return slices.Compact(candidates)
+}
+
+// cachedNames are the names parsed from a file, which is parsed again only
+// when its size or modification time change.
+type cachedNames struct {
+	mu      sync.Mutex
+	size    int64
+	modTime time.Time
+	names   []string
+}
+
+// read returns the names parse finds in the file at path.
+func (c *cachedNames) read(path string, parse func(data string) []string) []string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.names != nil && info.Size() == c.size && info.ModTime().Equal(c.modTime) {
+		return c.names
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	c.names = append([]string{}, parse(string(data))...)
+	c.size, c.modTime = info.Size(), info.ModTime()
+	return c.names
+}
+
+// passwdUsers are the user names in the contents of /etc/passwd.
+func passwdUsers(data string) []string {
+	var names []string
+	for _, line := range strings.Split(data, "\n") {
+		name, _, _ := strings.Cut(line, ":")
+		if name != "" && !strings.HasPrefix(name, "#") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// hostsNames are the host names and aliases in the contents of /etc/hosts,
+// the fields after the address of each line.
+func hostsNames(data string) []string {
+	var names []string
+	for _, line := range strings.Split(data, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if fields := strings.Fields(line); len(fields) > 1 {
+			names = append(names, fields[1:]...)
+		}
+	}
+	return names
+}
+
+func (a *AutoComplete) Do(line []rune, pos int) ([][]rune, int) {
+	prefix := string(line[:pos])
+
+	if a.lastPrefix == prefix {
+		a.tabCount++
+	} else {
+		a.lastPrefix = prefix
+		a.tabCount = 1
+	}
+
+	context := newCompletionContext(prefix)
+	word := context.word
+	matcher := a.matcher()
+	matches, fuzzy := getMatchAutocompletion(a.candidates(context, prefix), word, matcher)
+	if len(matches) == 0 {
+		a.bell()
+		return nil, 0
+	}
+
+	// a fuzzy match is only put in when it is the only one, the others
+	// are listed best first
+	replacement := matcher.commonPrefix(matches)
+	if fuzzy && len(matches) > 1 {
+		replacement = ""
+	}
+	only := len(matches) == 1
+	if !only && slices.Contains(matches, replacement) {
+		// the space of a complete candidate is not where the others go on
+		replacement = strings.TrimSuffix(replacement, " ")
+	}
+	if expanded := a.expand(replacement); only && expanded != replacement {
+		a.replace(line, pos, context, completionText(expanded, only, context.quote))
+		return nil, 0
+	}
+	if strings.HasPrefix(replacement, word) && len(replacement) > len(word) {
+		suffix := []rune(completionText(replacement[len(word):], only, context.quote))
+		return [][]rune{suffix}, pos + len(suffix)
+	}
+	if replacement != "" && !strings.HasPrefix(replacement, word) {
+		a.replace(line, pos, context, completionText(replacement, only, context.quote))
+		return nil, 0
+	}
+
+	if a.tabCount < 2 {
+		a.bell()
+		return nil, 0
+	}
+	a.tabCount = 0
+	a.list(matches, word)
+	return nil, 0
+}
+
+// expand replaces the ~ or ~user a completed word starts with and every
+// $name and ${name} in it with what they stand for. The shell does not
+// expand them itself, a word is put in expanded so that it runs as shown.
+func (a *AutoComplete) expand(word string) string {
+	if a.shell == nil {
+		return word
+	}
+
+	if name, rest, ok := strings.Cut(word, "/"); ok && strings.HasPrefix(name, "~") {
+		if home, err := a.shell.tildeDirectory(name[1:]); err == nil {
+			word = strings.TrimSuffix(home, "/") + "/" + rest
+		}
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(word); i++ {
+		switch {
+		case word[i] == '$' && strings.HasPrefix(word[i+1:], "{"):
+			end := strings.IndexByte(word[i:], '}')
+			if end < 0 {
+				builder.WriteString(word[i:])
+				return builder.String()
+			}
+			builder.WriteString(a.shell.getenv(word[i+2 : i+end]))
+			i += end
+		case word[i] == '$':
+			end := i + 1
+			for end < len(word) && (word[end] == '_' || isLetter(word[end]) || (end > i+1 && isDigit(word[end]))) {
+				end++
+			}
+			if end == i+1 {
+				builder.WriteByte('$')
+				continue
+			}
+			builder.WriteString(a.shell.getenv(word[i+1 : end]))
+			i = end - 1
+		default:
+			builder.WriteByte(word[i])
+		}
+	}
+	return builder.String()
+}
+
+// replace puts replacement, already quoted, in place of the word before the
+// cursor, for matches that do not start with the word as it is typed. The line editor
+// can only set the whole line, which leaves the cursor at its end.
+func (a *AutoComplete) replace(line []rune, pos int, context completionContext, replacement string) {
+	if a.readline == nil {
+		return
+	}
+	prefix := string(line[:pos])
+	before := prefix[:context.start]
+	if context.quote != 0 {
+		before += string(context.quote)
+	}
+	a.readline.Operation.SetBuffer(before + replacement + string(line[pos:]))
+}
+
+func (a *AutoComplete) bell() {
+	if a.readline != nil {
+		a.readline.Terminal.Bell()
+	}
+}
+
+// list prints the candidates below the line, without the space after them
+// and without the directory the word is in, asking first when there are
+// more than completionQueryItems.
+func (a *AutoComplete) list(matches []string, word string) {
+	if a.readline == nil {
+		return
+	}
+	terminal := a.readline.Terminal
+	defer a.readline.Refresh()
+
+	if len(matches) > completionQueryItems {
+		fmt.Fprintf(terminal, "\nDisplay all %d possibilities? (y or n)", len(matches))
+		if !a.confirm() {
+			fmt.Fprintln(terminal)
+			return
+		}
+	}
+
+	dir := word[:strings.LastIndex(word, "/")+1]
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		names = append(names, strings.TrimSuffix(match[len(dir):], " "))
+	}
+
+	width := 80
+	if a.shell != nil {
+		width = a.shell.terminalWidth()
+	}
+	fmt.Fprint(terminal, "\n"+formatColumns(names, width))
+}
+
+// confirm waits for the answer to the question, like readline anything but
+// y, n, space and delete is ignored.
+func (a *AutoComplete) confirm() bool {
+	for {
+		switch a.readline.Terminal.ReadRune() {
+		case 'y', 'Y', ' ':
+			return true
+		case 'n', 'N', readline.CharBackspace, readline.CharDelete, readline.CharInterrupt, 0:
+			return false
+		}
+	}
+}
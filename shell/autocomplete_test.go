@@ -0,0 +1,222 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAutocomplete(t *testing.T) {
+	autocomplete := &AutoComplete{}
+
+	autocompletions, pos := autocomplete.Do([]rune("ech"), 3)
+
+	if len(autocompletions) != 1 {
+		t.Errorf("expected to found one autocompletion")
+	}
+
+	if !reflect.DeepEqual(autocompletions[0], []rune("o ")) {
+		t.Error("Should auotcomplete \"o \"")
+	}
+
+	if pos != 5 {
+		t.Errorf("should be at position 5")
+	}
+
+}
+
+func TestAutocompletePaths(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "map.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "my file.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "it's.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, `say "hi"`), nil, 0644)
+	os.Mkdir(filepath.Join(dir, "shared drive"), 0755)
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "src", "file.go"), nil, 0644)
+	os.Symlink(filepath.Join(dir, "src"), filepath.Join(dir, "link"))
+
+	cases := []Case{
+		{input: "ech", output: "o ", name: "command"},
+		{input: "cat mai", output: "n.go ", name: "file"},
+		{input: "cd sr", output: "c/", name: "directory"},
+		{input: "cd li", output: "nk/", name: "symlink to directory"},
+		{input: "cat src/f", output: "ile.go ", name: "inside directory"},
+		{input: "cat " + dir + "/mai", output: "n.go ", name: "absolute path"},
+		{input: "cat .h", output: "idden ", name: "hidden file"},
+		{input: "cat 'mai", output: "n.go' ", name: "open quote"},
+		{input: "cat my", output: "\\ file.txt ", name: "escaped space"},
+		{input: "cat 'it", output: "'\\''s.txt' ", name: "quote in single quotes"},
+		{input: "cat \"say", output: " \\\"hi\\\"\" ", name: "quote in double quotes"},
+		{input: "cd 'sh", output: "ared drive/", name: "directory keeps the quote open"},
+		{input: "cat my\\ mai", output: "", name: "no match"},
+		{input: "echo '$HO", output: "", name: "no variables in single quotes"},
+		{input: "histdb --se", output: "ssion ", name: "builtin flag"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			shell := New(Options{Dir: dir, Env: []string{"HOME=" + dir, "PATH="}})
+			autocomplete := &AutoComplete{shell: shell}
+
+			line := []rune(testCase.input)
+			autocompletions, _ := autocomplete.Do(line, len(line))
+
+			got := ""
+			if len(autocompletions) == 1 && string(autocompletions[0]) != "\x07" {
+				got = string(autocompletions[0])
+			}
+			if got != testCase.output {
+				t.Errorf("Expected completion %q, got: %q", testCase.output, autocompletions)
+			}
+		})
+	}
+}
+
+func TestCompletionExpandsWords(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), nil, 0644)
+
+	var output bytes.Buffer
+	shell := New(Options{
+		Stdin:  strings.NewReader("echo ~/mai\t\necho a${HO\t\ncd $HO\t\npwd\n"),
+		Stdout: &output,
+		Stderr: &output,
+		Dir:    "/",
+		Env:    []string{"HOME=" + dir, "PATH="},
+	})
+	shell.startCli()
+
+	// the shell has no tilde or parameter expansion, the words are put in
+	// expanded so that they run
+	expected := dir + "/main.go\na" + dir + "/\n" + dir + "\n"
+	if output.String() != expected {
+		t.Errorf("Expected result to be %q, got: %q", expected, output.String())
+	}
+}
+
+func TestFindCommonPrefix(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  []string
+		output string
+	}{
+		{name: "none", input: nil, output: ""},
+		{name: "single", input: []string{"main.go "}, output: "main.go "},
+		{name: "shared", input: []string{"main.go ", "map.txt "}, output: "ma"},
+		{name: "contained is not a prefix", input: []string{"a ", "ba "}, output: ""},
+		{name: "one is a prefix of the other", input: []string{"src/", "src/a "}, output: "src/"},
+		{name: "multibyte", input: []string{"é ", "è "}, output: ""},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := findCommonPrefix(testCase.input); got != testCase.output {
+				t.Errorf("Expected %q, got: %q", testCase.output, got)
+			}
+		})
+	}
+}
+
+func TestFormatColumns(t *testing.T) {
+	cases := []struct {
+		name   string
+		input  []string
+		width  int
+		output string
+	}{
+		{name: "one line", input: []string{"a", "bb", "c"}, width: 80, output: "a   bb  c\n"},
+		{name: "down the columns", input: []string{"a", "b", "c", "d", "e"}, width: 9, output: "a  c  e\nb  d\n"},
+		{name: "narrow", input: []string{"long", "names"}, width: 3, output: "long\nnames\n"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if got := formatColumns(testCase.input, testCase.width); got != testCase.output {
+				t.Errorf("Expected %q, got: %q", testCase.output, got)
+			}
+		})
+	}
+}
+
+func TestCompletionListInLineEditor(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "map.txt"), nil, 0644)
+	for i := range completionQueryItems + 1 {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%03d", i)), nil, 0644)
+	}
+
+	cases := []struct {
+		name   string
+		input  string
+		output string
+		hidden string
+	}{
+		{name: "list", input: "echo ma\t\t\n", output: "main.go  map.txt\n"},
+		{name: "common prefix", input: "echo m\t\n", output: "ma\n", hidden: "main.go  map.txt"},
+		{name: "declined", input: "echo file\t\tn\n", output: "Display all 101 possibilities? (y or n)", hidden: "file000  file001"},
+		{name: "accepted", input: "echo file\t\ty\n", output: "file000  file013"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			shell := New(Options{Stdin: strings.NewReader(testCase.input), Stdout: &output, Stderr: &output, Dir: dir, Env: []string{"PATH=", "COLUMNS=80"}})
+			shell.startCli()
+
+			if !strings.Contains(output.String(), testCase.output) {
+				t.Errorf("Expected output to contain %q, got: %q", testCase.output, output.String())
+			}
+			if testCase.hidden != "" && strings.Contains(output.String(), testCase.hidden) {
+				t.Errorf("Expected output not to contain %q, got: %q", testCase.hidden, output.String())
+			}
+		})
+	}
+}
+
+func TestPasswdUsersAndHostsNames(t *testing.T) {
+	passwd := "root:x:0:0:root:/root:/bin/sh\n# comment\nalice:x:1000:1000::/home/alice:/bin/sh\n"
+	if got := passwdUsers(passwd); !slices.Equal(got, []string{"root", "alice"}) {
+		t.Errorf("Expected the users of passwd, got: %q", got)
+	}
+
+	hosts := "127.0.0.1 localhost\n# 10.0.0.1 hidden\n10.0.0.2 build build.lan # builder\n"
+	if got := hostsNames(hosts); !slices.Equal(got, []string{"localhost", "build", "build.lan"}) {
+		t.Errorf("Expected the names of hosts, got: %q", got)
+	}
+
+	autocomplete := &AutoComplete{}
+	if got := autocomplete.nameCandidates("me@", hostsNames(hosts), "bu", " "); !slices.Equal(got, []string{"me@build ", "me@build.lan "}) {
+		t.Errorf("Expected the matching hosts, got: %q", got)
+	}
+}
+
+func TestCachedNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	os.WriteFile(path, []byte("10.0.0.1 one\n"), 0644)
+
+	parsed := 0
+	parse := func(data string) []string {
+		parsed++
+		return hostsNames(data)
+	}
+
+	var cache cachedNames
+	cache.read(path, parse)
+	if got := cache.read(path, parse); parsed != 1 || !slices.Equal(got, []string{"one"}) {
+		t.Errorf("Expected the file to be parsed once, got %d parses and %q", parsed, got)
+	}
+
+	os.WriteFile(path, []byte("10.0.0.1 one two\n"), 0644)
+	if got := cache.read(path, parse); parsed != 2 || !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("Expected a changed file to be parsed again, got %d parses and %q", parsed, got)
+	}
+}
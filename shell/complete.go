@@ -0,0 +1,327 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	completeUsage = "complete: usage: complete [-p] [-r] [-abcdf] [-o option] [-W wordlist] [-F function] [-C command] [name ...]"
+	compgenUsage  = "compgen: usage: compgen [-abcdf] [-o option] [-W wordlist] [-F function] [-C command] [word]"
+)
+
+// completionSpec is what complete registered for a command. The candidates
+// of every part are put together.
+type completionSpec struct {
+	// actions are the letters of -a, -b, -c, -d and -f: aliases, builtins,
+	// commands, directories and files.
+	actions  string
+	words    string
+	function string
+	command  string
+	// options are the -o options: filenames marks directories with a slash,
+	// dirnames and default complete directories or files when nothing else
+	// matched.
+	options []string
+}
+
+var completionOptions = []string{"filenames", "dirnames", "default"}
+
+func (spec completionSpec) hasOption(option string) bool {
+	return slices.Contains(spec.options, option)
+}
+
+// String prints the spec as the complete command that creates it.
+func (spec completionSpec) String() string {
+	parts := []string{"complete"}
+	for _, action := range spec.actions {
+		parts = append(parts, "-"+string(action))
+	}
+	for _, option := range spec.options {
+		parts = append(parts, "-o", option)
+	}
+	if spec.words != "" {
+		parts = append(parts, "-W", singleQuote(spec.words))
+	}
+	if spec.function != "" {
+		parts = append(parts, "-F", spec.function)
+	}
+	if spec.command != "" {
+		parts = append(parts, "-C", singleQuote(spec.command))
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseCompletionSpec reads the options complete and compgen share, it
+// stops at the first argument that is not one of them.
+func parseCompletionSpec(args []string, usage func(string) error) (completionSpec, []string, error) {
+	var spec completionSpec
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		flag := args[0]
+		args = args[1:]
+
+		switch flag {
+		case "-a", "-b", "-c", "-d", "-f":
+			if !strings.Contains(spec.actions, flag[1:]) {
+				spec.actions += flag[1:]
+			}
+			continue
+		case "-o", "-W", "-F", "-C":
+		default:
+			return spec, nil, usage(flag + ": invalid option")
+		}
+
+		if len(args) == 0 {
+			return spec, nil, usage(flag + ": option requires an argument")
+		}
+		value := args[0]
+		args = args[1:]
+
+		switch flag {
+		case "-o":
+			if !slices.Contains(completionOptions, value) {
+				return spec, nil, usage(value + ": invalid option name")
+			}
+			spec.options = append(spec.options, value)
+		case "-W":
+			spec.words = value
+		case "-F":
+			spec.function = value
+		case "-C":
+			spec.command = value
+		}
+	}
+	return spec, args, nil
+}
+
+// completionWords are the arguments given to -F functions and -C commands:
+// the command name, the word being completed and the word before it.
+type completionWords struct {
+	command  string
+	word     string
+	previous string
+	line     string
+}
+
+// runCompletion runs a -F function or a -C command with $COMP_LINE and
+// $COMP_POINT set and returns the lines it printed. There are no arrays to
+// fill a COMPREPLY with, so the output is the list of candidates.
+func (shell *Shell) runCompletion(function string, command string, words completionWords) []string {
+	saved := map[string]*string{}
+	for key, value := range map[string]string{"COMP_LINE": words.line, "COMP_POINT": strconv.Itoa(len(words.line))} {
+		if old, ok := shell.lookupEnv(key); ok {
+			saved[key] = &old
+		} else {
+			saved[key] = nil
+		}
+		shell.setenv(key, value)
+	}
+	defer func() {
+		for key, value := range saved {
+			if value == nil {
+				delete(shell.env, key)
+			} else {
+				shell.setenv(key, *value)
+			}
+		}
+	}()
+
+	args := []string{words.command, words.word, words.previous}
+	output := ""
+	if function != "" {
+		definition, ok := shell.functions[function]
+		if !ok {
+			return nil
+		}
+		output, _ = shell.callFunction(definition, args)
+	} else {
+		line := command
+		for _, arg := range args {
+			line += " " + singleQuote(arg)
+		}
+		output = shell.commandOutput(line)
+	}
+
+	return strings.Fields(output)
+}
+
+// generate returns the candidates of spec that start with words.word,
+// without the space or slash the completer adds.
+func (shell *Shell) generate(spec completionSpec, words completionWords) []string {
+	completer := &AutoComplete{shell: shell}
+	word := words.word
+	var candidates []string
+
+	// path and command candidates end with the character added after them
+	trimmed := func(items []string) {
+		for _, item := range items {
+			candidates = append(candidates, item[:len(item)-1])
+		}
+	}
+
+	for _, action := range spec.actions {
+		switch action {
+		case 'a':
+			for name := range shell.aliases {
+				candidates = append(candidates, name)
+			}
+		case 'b':
+			for _, name := range shell.Builtins().Names() {
+				candidates = append(candidates, string(name))
+			}
+		case 'c':
+			trimmed(completer.commandCandidates(word))
+		case 'd':
+			trimmed(completer.pathCandidates(word, true))
+		case 'f':
+			trimmed(completer.pathCandidates(word, false))
+		}
+	}
+	candidates = append(candidates, strings.Fields(spec.words)...)
+	if spec.function != "" || spec.command != "" {
+		candidates = append(candidates, shell.runCompletion(spec.function, spec.command, words)...)
+	}
+
+	matcher := shell.completionMatcher()
+	matches := []string{}
+	for _, candidate := range candidates {
+		if matcher.matches(candidate, word) && !slices.Contains(matches, candidate) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// specCandidates completes the word with the spec complete registered for
+// the command, ok is false when there is none.
+func (a *AutoComplete) specCandidates(context completionContext, line string) ([]string, bool) {
+	if a.shell == nil || context.command == "" || context.redirect {
+		return nil, false
+	}
+	spec, ok := a.shell.completions[context.command]
+	if !ok {
+		return nil, false
+	}
+
+	words := completionWords{command: context.command, word: context.word, line: line}
+	previous := newCompletionContext(strings.TrimRight(line[:context.start], " "))
+	if context.argIndex > 0 {
+		words.previous = previous.word
+	}
+
+	matches := a.shell.generate(spec, words)
+	filenames := spec.hasOption("filenames") || strings.ContainsAny(spec.actions, "df")
+
+	candidates := []string{}
+	for _, match := range matches {
+		if filenames && a.isDirectory(match) {
+			candidates = append(candidates, match+"/")
+		} else {
+			candidates = append(candidates, match+" ")
+		}
+	}
+
+	if len(candidates) == 0 && spec.hasOption("dirnames") {
+		candidates = a.pathCandidates(context.word, true)
+	}
+	if len(candidates) == 0 && spec.hasOption("default") {
+		candidates = a.pathCandidates(context.word, false)
+	}
+	return candidates, true
+}
+
+func (a *AutoComplete) isDirectory(name string) bool {
+	if strings.HasPrefix(name, "~") {
+		user, rest, _ := strings.Cut(name[1:], "/")
+		home, err := a.shell.tildeDirectory(user)
+		if err != nil {
+			return false
+		}
+		name = filepath.Join(home, rest)
+	}
+	info, err := os.Stat(a.shell.resolvePath(name))
+	return err == nil && info.IsDir()
+}
+
+func (shell *Shell) handleCompleteCommand(args []string) (string, error) {
+	usage := func(message string) error {
+		return &statusError{status: 2, message: "complete: " + message + "\n" + completeUsage}
+	}
+
+	print, remove := len(args) == 0, false
+	for len(args) > 0 && (args[0] == "-p" || args[0] == "-r") {
+		print = print || args[0] == "-p"
+		remove = remove || args[0] == "-r"
+		args = args[1:]
+	}
+
+	if print || remove {
+		names := args
+		if len(names) == 0 {
+			for name := range shell.completions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+
+		lines := []string{}
+		for _, name := range names {
+			spec, ok := shell.completions[name]
+			if !ok {
+				return strings.Join(lines, "\n"), fmt.Errorf("complete: %s: no completion specification", name)
+			}
+			if remove {
+				delete(shell.completions, name)
+				continue
+			}
+			lines = append(lines, spec.String()+" "+name)
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	spec, names, err := parseCompletionSpec(args, usage)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", usage("missing command name")
+	}
+
+	if shell.completions == nil {
+		shell.completions = map[string]completionSpec{}
+	}
+	for _, name := range names {
+		shell.completions[name] = spec
+	}
+	return "", nil
+}
+
+func (shell *Shell) handleCompgenCommand(args []string) (string, error) {
+	usage := func(message string) error {
+		return &statusError{status: 2, message: "compgen: " + message + "\n" + compgenUsage}
+	}
+
+	spec, rest, err := parseCompletionSpec(args, usage)
+	if err != nil {
+		return "", err
+	}
+	if len(rest) > 1 {
+		return "", usage("too many arguments")
+	}
+
+	words := completionWords{command: "compgen"}
+	if len(rest) == 1 {
+		words.word = rest[0]
+	}
+
+	matches := shell.generate(spec, words)
+	if len(matches) == 0 {
+		return "", &statusError{status: 1}
+	}
+	return strings.Join(matches, "\n"), nil
+}
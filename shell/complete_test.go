@@ -0,0 +1,86 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompleteSpecs(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	os.WriteFile(filepath.Join(dir, "setup.py"), nil, 0644)
+
+	cases := []struct {
+		name   string
+		setup  string
+		line   string
+		output string
+	}{
+		{name: "word list", setup: "complete -W 'status commit checkout' git", line: "git st", output: "atus "},
+		{name: "command word is not completed by the spec", setup: "complete -W 'status' git", line: "gi", output: ""},
+		{name: "function", setup: "_make() { echo build; echo test; }; complete -F _make make", line: "make b", output: "uild "},
+		{name: "command", setup: "complete -C 'echo alpha beta; true' tool", line: "tool al", output: "pha "},
+		{name: "dirnames fallback", setup: "complete -W 'zzz' -o dirnames x", line: "x sr", output: "c/"},
+		{name: "no spec falls back to files", setup: "complete -W 'status' git", line: "cat setup", output: ".py "},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			shell := New(Options{Stdout: &output, Stderr: &output, Dir: dir, Env: []string{"PATH="}})
+			shell.Eval(testCase.setup)
+			if output.Len() > 0 {
+				t.Fatalf("Unexpected output from setup: %q", output.String())
+			}
+
+			autocomplete := &AutoComplete{shell: shell}
+			line := []rune(testCase.line)
+			autocompletions, _ := autocomplete.Do(line, len(line))
+
+			got := ""
+			if len(autocompletions) == 1 && string(autocompletions[0]) != "\x07" {
+				got = string(autocompletions[0])
+			}
+			if got != testCase.output {
+				t.Errorf("Expected completion %q, got: %q", testCase.output, autocompletions)
+			}
+		})
+	}
+}
+
+func TestCompleteAndCompgenCommands(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "src"), 0755)
+	os.Mkdir(filepath.Join(dir, "scripts"), 0755)
+	os.WriteFile(filepath.Join(dir, "setup.py"), nil, 0644)
+
+	cases := []Case{
+		{input: "complete -W 'a b' -o filenames git; complete -F _f make; complete -p", output: "complete -o filenames -W 'a b' git\ncomplete -F _f make", name: "print"},
+		{input: "complete -W a git; complete -r git; complete -p git", err: "complete: git: no completion specification", name: "remove"},
+		{input: "complete -W", err: "complete: -W: option requires an argument\n" + completeUsage, name: "missing argument"},
+		{input: "compgen -W 'alpha beta almond' al", output: "alpha\nalmond", name: "compgen words"},
+		{input: "compgen -d s", output: "scripts\nsrc", name: "compgen directories"},
+		{input: "_f() { echo one; echo two; }; compgen -F _f t", output: "two", name: "compgen function"},
+		{input: "compgen -W 'a' z || echo none", output: "none", name: "compgen no match"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := New(Options{Stdout: &output, Stderr: &errout, Dir: dir, Env: []string{"PATH="}})
+
+			shell.Eval(testCase.input)
+
+			if got := strings.TrimRight(output.String(), "\n"); got != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, got)
+			}
+			if got := strings.TrimRight(errout.String(), "\n"); got != testCase.err {
+				t.Errorf("Expected error to be %q, got: %q", testCase.err, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package shell
+
+import (
+	"slices"
+	"strings"
+)
+
+// completionContext describes the word under the cursor, it is derived from
+// the tokens of the Lexar so it follows the same rules as the parser.
+type completionContext struct {
+	// word is the text of the word up to the cursor, without quotes and
+	// escapes, start is where it begins in the line.
+	word  string
+	start int
+	// command is the command word of the simple command the cursor is in,
+	// empty when the cursor is on the command word itself.
+	command string
+	// argIndex counts the arguments before the word, redirections and
+	// their targets are not arguments.
+	argIndex int
+	// redirect is set for the target of a redirection operator.
+	redirect bool
+	// quote is ' or " when the word has an open quote.
+	quote byte
+}
+
+func newCompletionContext(line string) completionContext {
+	var context completionContext
+	lexar := newLexar(line)
+	inWord, afterRedirect := false, false
+
+	endWord := func() {
+		if !inWord {
+			return
+		}
+		switch {
+		case afterRedirect:
+			afterRedirect = false
+		case context.command == "":
+			context.command = context.word
+		default:
+			context.argIndex++
+		}
+		inWord = false
+	}
+
+	for {
+		token := lexar.nextToken()
+		switch token.tokenType {
+		case EOF:
+			context.redirect = afterRedirect
+			if !inWord {
+				context.word, context.start = "", len(line)
+			}
+			context.quote = openQuote(line[context.start:])
+			return context
+		case STRING:
+			if !inWord {
+				context.word, context.start = "", token.pos
+			}
+			context.word += token.literal
+			inWord = true
+		case SPACE:
+			endWord()
+		case REDIRECT:
+			endWord()
+			afterRedirect = true
+		case PIPE, LIST:
+			// the next word is a command again
+			endWord()
+			context.command, context.argIndex, afterRedirect = "", 0, false
+		}
+	}
+}
+
+// openQuote returns the quote that is still open at the end of word.
+func openQuote(word string) byte {
+	var quote byte
+	for i := 0; i < len(word); i++ {
+		switch {
+		case quote == 0 && word[i] == '\\':
+			i++
+		case quote == '"' && word[i] == '\\':
+			i++
+		case quote == 0 && (word[i] == '\'' || word[i] == '"'):
+			quote = word[i]
+		case quote != 0 && word[i] == quote:
+			quote = 0
+		}
+	}
+	return quote
+}
+
+// quoteCompletion writes text as it has to be typed in the quoting the word
+// is in: inside single quotes a quote ends them, is escaped and opens them
+// again, inside double quotes " and \ are escaped, and outside quotes the
+// metacharacters of the Lexar and the ! of history expansion are.
+func quoteCompletion(text string, quote byte) string {
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(text, "'", `'\''`)
+	case '"':
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '!' || (text[i] != 0 && slices.Contains(metacharacters, text[i])) {
+			builder.WriteByte('\\')
+		}
+		builder.WriteByte(text[i])
+	}
+	return builder.String()
+}
+
+// completionText is what is typed for text, the end of a candidate. When
+// the candidate is the only one its trailing space ends the word, it is not
+// escaped and an open quote is closed before it.
+func completionText(text string, only bool, quote byte) string {
+	if !only || !strings.HasSuffix(text, " ") {
+		return quoteCompletion(text, quote)
+	}
+	text = quoteCompletion(strings.TrimSuffix(text, " "), quote)
+	if quote != 0 {
+		text += string(quote)
+	}
+	return text + " "
+}
+
+// flagCandidates are the documented options of a builtin.
+func flagCandidates(spec CommandSpec) []string {
+	candidates := []string{}
+	for _, flag := range spec.Flags {
+		candidates = append(candidates, flag.Name+" ")
+	}
+	return candidates
+}
+
+// candidates picks what to complete from the context of line: variables
+// after a $ outside single quotes, users after a ~, hosts after an @ in an
+// argument when one matches, commands on the command word, files after a redirection, what complete registered for the command,
+// and for the arguments of a builtin what its CommandSpec asks for. Other
+// commands take files.
+func (a *AutoComplete) candidates(context completionContext, line string) []string {
+	word := context.word
+	if context.quote != '\'' {
+		if candidates, ok := a.variableCandidates(word); ok {
+			return candidates
+		}
+	}
+	if strings.HasPrefix(word, "~") && len(word) > 1 && !strings.Contains(word, "/") {
+		return a.userCandidates(word)
+	}
+	if context.command != "" {
+		if candidates := a.hostCandidates(word); len(candidates) > 0 {
+			return candidates
+		}
+	}
+	if candidates, ok := a.specCandidates(context, line); ok {
+		return candidates
+	}
+	if context.redirect {
+		return a.pathCandidates(word, false)
+	}
+	if context.command == "" {
+		if strings.Contains(word, "/") {
+			return a.pathCandidates(word, false)
+		}
+		return a.commandCandidates(word)
+	}
+
+	spec, ok := a.registry().Lookup(Command(context.command))
+	if !ok {
+		return a.pathCandidates(word, false)
+	}
+	if strings.HasPrefix(word, "-") && len(spec.Flags) > 0 {
+		return flagCandidates(spec)
+	}
+
+	switch spec.Completion {
+	case CompleteFiles:
+		return a.pathCandidates(word, false)
+	case CompleteDirectories:
+		return a.pathCandidates(word, true)
+	case CompleteCommands:
+		if strings.Contains(word, "/") {
+			return a.pathCandidates(word, false)
+		}
+		return a.commandCandidates(word)
+	}
+	return nil
+}
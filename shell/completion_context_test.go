@@ -0,0 +1,66 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompletionContext(t *testing.T) {
+	cases := []struct {
+		line    string
+		context completionContext
+	}{
+		{line: "ec", context: completionContext{word: "ec"}},
+		{line: "echo a b", context: completionContext{word: "b", start: 7, command: "echo", argIndex: 1}},
+		{line: "cat file | gr", context: completionContext{word: "gr", start: 11}},
+		{line: "sort < in", context: completionContext{word: "in", start: 7, command: "sort", redirect: true}},
+		{line: "echo $HO", context: completionContext{word: "$HO", start: 5, command: "echo"}},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.line, func(t *testing.T) {
+			got := newCompletionContext(testCase.line)
+			if got != testCase.context {
+				t.Errorf("Expected %+v, got: %+v", testCase.context, got)
+			}
+		})
+	}
+}
+
+func TestAutocompleteRules(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "nested"), 0755)
+	os.WriteFile(filepath.Join(dir, "output.log"), nil, 0644)
+
+	cases := []Case{
+		{input: "echo a | ech", output: "o ", name: "command after pipe"},
+		{input: "cd ne", output: "sted/", name: "cd completes directories"},
+		{input: "echo a > out", output: "put.log ", name: "file after redirect"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			shell := New(Options{Dir: dir, Env: []string{"HOME=" + dir, "PATH="}})
+			autocomplete := &AutoComplete{shell: shell}
+
+			line := []rune(testCase.input)
+			autocompletions, _ := autocomplete.Do(line, len(line))
+
+			got := ""
+			if len(autocompletions) == 1 && string(autocompletions[0]) != "\x07" {
+				got = string(autocompletions[0])
+			}
+			if got != testCase.output {
+				t.Errorf("Expected completion %q, got: %q", testCase.output, autocompletions)
+			}
+		})
+	}
+
+	shell := New(Options{Dir: dir, Env: []string{"PATH="}})
+	context := newCompletionContext("history -")
+	candidates := (&AutoComplete{shell: shell}).candidates(context, "history -")
+	if len(candidates) != 8 || candidates[0] != "-c " {
+		t.Errorf("Expected the flags of history, got: %q", candidates)
+	}
+}
@@ -0,0 +1,145 @@
+package shell
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// completionMatcher decides which candidates complete a word. It is set up
+// from $COMPLETION_IGNORE_CASE, $COMPLETION_MAP_CASE and $COMPLETION_FUZZY,
+// each is on when set to anything, like readline's completion-ignore-case
+// and completion-map-case.
+type completionMatcher struct {
+	ignoreCase bool
+	// mapCase treats - and _ as the same character.
+	mapCase bool
+	// fuzzy lets the word match any candidate that has its characters in
+	// order, those are only offered when no candidate starts with the word.
+	fuzzy bool
+}
+
+func (shell *Shell) completionMatcher() completionMatcher {
+	return completionMatcher{
+		ignoreCase: shell.getenv("COMPLETION_IGNORE_CASE") != "",
+		mapCase:    shell.getenv("COMPLETION_MAP_CASE") != "",
+		fuzzy:      shell.getenv("COMPLETION_FUZZY") != "",
+	}
+}
+
+// strict is set when a candidate has to start with the word byte for byte.
+func (m completionMatcher) strict() bool {
+	return !m.ignoreCase && !m.mapCase && !m.fuzzy
+}
+
+// fold maps every rune to the one it is compared as, one rune for one so
+// positions in the folded text are positions in s.
+func (m completionMatcher) fold(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		if m.ignoreCase {
+			runes[i] = unicode.ToLower(r)
+		}
+		if m.mapCase && runes[i] == '_' {
+			runes[i] = '-'
+		}
+	}
+	return runes
+}
+
+func (m completionMatcher) hasPrefix(s string, prefix string) bool {
+	if m.strict() {
+		return strings.HasPrefix(s, prefix)
+	}
+	folded, foldedPrefix := m.fold(s), m.fold(prefix)
+	return len(folded) >= len(foldedPrefix) && slices.Equal(folded[:len(foldedPrefix)], foldedPrefix)
+}
+
+// matches is what the candidate lists are filtered with, it accepts the
+// candidates fuzzy matching finds as well.
+func (m completionMatcher) matches(s string, word string) bool {
+	if m.hasPrefix(s, word) {
+		return true
+	}
+	_, ok := m.fuzzyScore(s, word)
+	return m.fuzzy && ok
+}
+
+// fuzzyScore looks for the characters of pattern in order in s. Characters
+// that follow each other, that start a word after a separator or that come
+// early score higher.
+func (m completionMatcher) fuzzyScore(s string, pattern string) (int, bool) {
+	text, wanted := m.fold(s), m.fold(pattern)
+
+	score, previous, j := 0, -1, 0
+	for i := 0; i < len(text) && j < len(wanted); i++ {
+		if text[i] != wanted[j] {
+			continue
+		}
+		score++
+		if previous >= 0 && previous == i-1 {
+			score += 4
+		}
+		if i == 0 || strings.ContainsRune("/-_. ", text[i-1]) {
+			score += 3
+		}
+		if previous < 0 {
+			score -= i
+		}
+		previous = i
+		j++
+	}
+	return score, j == len(wanted)
+}
+
+// commonPrefix is the longest prefix the items share as the matcher compares
+// them, spelled as in the first item.
+func (m completionMatcher) commonPrefix(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	if m.strict() {
+		return findCommonPrefix(items)
+	}
+
+	first := []rune(items[0])
+	length := len(first)
+	folded := m.fold(items[0])
+	for _, item := range items[1:] {
+		other := m.fold(item)
+		common := 0
+		for common < length && common < len(other) && folded[common] == other[common] {
+			common++
+		}
+		length = common
+	}
+	return string(first[:length])
+}
+
+// getMatchAutocompletion keeps the candidates that start with selected, they
+// end with the space or slash to insert after them. When none does and the
+// matcher is fuzzy, the ones that contain the characters of selected are
+// kept instead, best first, and fuzzy is set.
+func getMatchAutocompletion(autocompleteData []string, selected string, matcher completionMatcher) (autocompletion []string, fuzzy bool) {
+	autocompletion = []string{}
+	for _, item := range autocompleteData {
+		if matcher.hasPrefix(item, selected) && item != selected {
+			autocompletion = append(autocompletion, item)
+		}
+	}
+	if len(autocompletion) > 0 || !matcher.fuzzy {
+		return autocompletion, false
+	}
+
+	scores := map[string]int{}
+	for _, item := range autocompleteData {
+		if score, ok := matcher.fuzzyScore(item, selected); ok {
+			scores[item] = score
+			autocompletion = append(autocompletion, item)
+		}
+	}
+	slices.SortStableFunc(autocompletion, func(a, b string) int {
+		return scores[b] - scores[a]
+	})
+	return autocompletion, true
+}
@@ -0,0 +1,68 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCompletionMatching(t *testing.T) {
+	candidates := []string{"Makefile ", "main.go ", "my_file ", "src/", "README.md "}
+
+	cases := []struct {
+		name    string
+		env     []string
+		word    string
+		matches []string
+		fuzzy   bool
+	}{
+		{name: "strict", word: "ma", matches: []string{"main.go "}},
+		{name: "ignore case", env: []string{"COMPLETION_IGNORE_CASE=on"}, word: "ma", matches: []string{"Makefile ", "main.go "}},
+		{name: "map case", env: []string{"COMPLETION_MAP_CASE=on"}, word: "my-", matches: []string{"my_file "}},
+		{name: "prefix before fuzzy", env: []string{"COMPLETION_FUZZY=on"}, word: "m", matches: []string{"main.go ", "my_file "}},
+		{name: "fuzzy ranking", env: []string{"COMPLETION_FUZZY=on"}, word: "fi", matches: []string{"my_file ", "Makefile "}, fuzzy: true},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			shell := New(Options{Env: testCase.env})
+			matches, fuzzy := getMatchAutocompletion(candidates, testCase.word, shell.completionMatcher())
+			if !slices.Equal(matches, testCase.matches) || fuzzy != testCase.fuzzy {
+				t.Errorf("Expected %q (fuzzy %v), got: %q (fuzzy %v)", testCase.matches, testCase.fuzzy, matches, fuzzy)
+			}
+		})
+	}
+}
+
+func TestCompletionReplacesWord(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "Makefile"), nil, 0644)
+	os.WriteFile(filepath.Join(dir, "my_notes.txt"), nil, 0644)
+
+	cases := []struct {
+		name   string
+		env    []string
+		input  string
+		output string
+	}{
+		{name: "ignore case", env: []string{"COMPLETION_IGNORE_CASE=1"}, input: "echo make\t\n", output: "Makefile\n"},
+		{name: "fuzzy", env: []string{"COMPLETION_FUZZY=1"}, input: "echo mnt\t\n", output: "my_notes.txt\n"},
+		{name: "off", input: "echo make\t\n", output: "make\n"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			env := append([]string{"PATH="}, testCase.env...)
+			shell := New(Options{Stdin: strings.NewReader(testCase.input), Stdout: &output, Stderr: &output, Dir: dir, Env: env})
+			shell.startCli()
+
+			if !strings.HasSuffix(output.String(), testCase.output) {
+				t.Errorf("Expected output to end with %q, got: %q", testCase.output, output.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package shell
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitRepository is what the prompt knows about the repository the shell is
+// in. It is kept in gitPromptCache until the working directory or the time
+// HEAD was written changes, switching branches rewrites HEAD.
+type gitRepository struct {
+	directory string
+	headTime  time.Time
+
+	gitDir   string
+	workTree string
+	// branch is the branch HEAD points to, or the short hash of a
+	// detached HEAD.
+	branch string
+	sha256 bool
+}
+
+// gitIndex is the parsed index, kept while the index file does not change.
+type gitIndex struct {
+	path    string
+	modTime time.Time
+	entries []gitIndexEntry
+}
+
+type gitIndexEntry struct {
+	path    string
+	modTime time.Time
+	size    uint32
+	mode    uint32
+	hash    []byte
+	// ignored is set for entries git does not compare with the work tree:
+	// unmerged stages, submodules and assume-unchanged or skip-worktree
+	// files.
+	ignored bool
+}
+
+// gitFileState is the result of hashing a file whose stat data did not match
+// the index, it holds while the file keeps the same time and size.
+type gitFileState struct {
+	modTime time.Time
+	size    int64
+	changed bool
+}
+
+// gitDirtyInterval is how long the dirty flag is kept while the index does
+// not change. Comparing the work tree stats every tracked file, this spares
+// it on every Enter while edits still show up a moment later.
+const gitDirtyInterval = 5 * time.Second
+
+type gitPromptCache struct {
+	repository *gitRepository
+	index      gitIndex
+	hashed     map[string]gitFileState
+	// dirty is the last result of gitDirty for index, checked is when it
+	// was computed.
+	dirty   bool
+	checked time.Time
+}
+
+// gitPrompt is the \g segment of the prompt: " (branch)" in a git work tree,
+// " (branch *)" when a tracked file differs from the index, and nothing
+// outside one. Everything is read from the .git directory, git is not run.
+func (shell *Shell) gitPrompt() string {
+	repository := shell.gitRepository()
+	if repository == nil {
+		return ""
+	}
+	if shell.gitDirty(repository) {
+		return " (" + repository.branch + " *)"
+	}
+	return " (" + repository.branch + ")"
+}
+
+func (shell *Shell) gitRepository() *gitRepository {
+	cache := &shell.git
+	directory := shell.workingDirectory()
+
+	if cache.repository != nil && cache.repository.directory == directory {
+		info, err := os.Stat(filepath.Join(cache.repository.gitDir, "HEAD"))
+		if err == nil && info.ModTime().Equal(cache.repository.headTime) {
+			return cache.repository
+		}
+	}
+
+	cache.repository = findGitRepository(directory)
+	return cache.repository
+}
+
+// findGitRepository looks for .git in directory and its parents. A .git file
+// is a worktree or a submodule, it names the real git directory.
+func findGitRepository(directory string) *gitRepository {
+	for dir := directory; ; dir = filepath.Dir(dir) {
+		dotGit := filepath.Join(dir, ".git")
+		info, err := os.Stat(dotGit)
+		if err == nil {
+			gitDir := dotGit
+			if !info.IsDir() {
+				data, err := os.ReadFile(dotGit)
+				target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+				if err != nil || !ok {
+					return nil
+				}
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(dir, target)
+				}
+				gitDir = target
+			}
+			return readGitRepository(directory, gitDir, dir)
+		}
+		if filepath.Dir(dir) == dir {
+			return nil
+		}
+	}
+}
+
+func readGitRepository(directory string, gitDir string, workTree string) *gitRepository {
+	headPath := filepath.Join(gitDir, "HEAD")
+	info, err := os.Stat(headPath)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(headPath)
+	if err != nil {
+		return nil
+	}
+
+	head := strings.TrimSpace(string(data))
+	branch, ok := strings.CutPrefix(head, "ref: ")
+	if ok {
+		branch = strings.TrimPrefix(branch, "refs/heads/")
+	} else {
+		branch = head[:min(len(head), 7)]
+	}
+
+	// worktrees share the config of the main git directory
+	config := filepath.Join(gitDir, "config")
+	if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		config = filepath.Join(gitDir, strings.TrimSpace(string(common)), "config")
+	}
+	configData, _ := os.ReadFile(config)
+	useSHA256 := false
+	for _, line := range strings.Split(string(configData), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		if strings.EqualFold(strings.TrimSpace(key), "objectformat") {
+			useSHA256 = strings.TrimSpace(value) == "sha256"
+		}
+	}
+
+	return &gitRepository{
+		directory: directory,
+		headTime:  info.ModTime(),
+		gitDir:    gitDir,
+		workTree:  workTree,
+		branch:    branch,
+		sha256:    useSHA256,
+	}
+}
+
+// gitDirty compares the tracked files with the index like git status does:
+// a file is unchanged while its time and size match the index, otherwise its
+// contents are hashed. Changes that are staged but not committed are not
+// seen, that needs the objects of HEAD. The result is kept for the index of
+// the repository for gitDirtyInterval.
+func (shell *Shell) gitDirty(repository *gitRepository) bool {
+	cache := &shell.git
+	indexPath := filepath.Join(repository.gitDir, "index")
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		return false
+	}
+	if cache.index.path == indexPath && cache.index.modTime.Equal(info.ModTime()) && time.Since(cache.checked) < gitDirtyInterval {
+		return cache.dirty
+	}
+	if cache.index.path != indexPath || !cache.index.modTime.Equal(info.ModTime()) {
+		entries, err := readGitIndex(indexPath, repository.sha256)
+		if err != nil {
+			return false
+		}
+		cache.index = gitIndex{path: indexPath, modTime: info.ModTime(), entries: entries}
+		cache.hashed = map[string]gitFileState{}
+	}
+
+	cache.dirty, cache.checked = shell.workTreeChanged(repository), time.Now()
+	return cache.dirty
+}
+
+// workTreeChanged is set when a tracked file differs from the cached index.
+func (shell *Shell) workTreeChanged(repository *gitRepository) bool {
+	cache := &shell.git
+	for _, entry := range cache.index.entries {
+		if entry.ignored {
+			continue
+		}
+		path := filepath.Join(repository.workTree, filepath.FromSlash(entry.path))
+		file, err := os.Lstat(path)
+		if err != nil {
+			return true
+		}
+		if file.Size() != int64(entry.size) || executable(file.Mode()) != (entry.mode&0111 != 0) {
+			return true
+		}
+		// a file modified as late as the index was written may have changed
+		// again after it was added, git calls that racy and hashes it too
+		if file.ModTime().Equal(entry.modTime) && file.ModTime().Before(cache.index.modTime) {
+			continue
+		}
+
+		state, ok := cache.hashed[path]
+		if !ok || !state.modTime.Equal(file.ModTime()) || state.size != file.Size() {
+			state = gitFileState{modTime: file.ModTime(), size: file.Size(), changed: fileChanged(path, file, entry.hash, repository.sha256)}
+			cache.hashed[path] = state
+		}
+		if state.changed {
+			return true
+		}
+	}
+	return false
+}
+
+func executable(mode os.FileMode) bool {
+	return mode.IsRegular() && mode.Perm()&0111 != 0
+}
+
+// fileChanged hashes path as a git blob and compares it with the hash in the
+// index, the blob of a symlink is its target.
+func fileChanged(path string, info os.FileInfo, expected []byte, useSHA256 bool) bool {
+	var data []byte
+	var err error
+	if info.Mode()&os.ModeSymlink != 0 {
+		var target string
+		target, err = os.Readlink(path)
+		data = []byte(target)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return true
+	}
+
+	h := sha1.New()
+	if useSHA256 {
+		h = sha256.New()
+	}
+	h.Write([]byte("blob " + strconv.Itoa(len(data)) + "\x00"))
+	h.Write(data)
+	return !bytes.Equal(h.Sum(nil), expected)
+}
+
+var errGitIndex = errors.New("invalid git index")
+
+// readGitIndex reads the entries of an index file of version 2, 3 or 4, see
+// gitformat-index(5). Version 4 writes each path as the number of bytes to
+// drop from the previous one and the rest of the path.
+func readGitIndex(path string, useSHA256 bool) ([]gitIndexEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, errGitIndex
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, errGitIndex
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	hashSize := sha1.Size
+	if useSHA256 {
+		hashSize = sha256.Size
+	}
+
+	// every entry takes at least 62 bytes, a corrupt count must not make
+	// the list any larger than the file can hold
+	entries := make([]gitIndexEntry, 0, min(int(count), len(data)/62))
+	offset, previous := 12, ""
+	for range count {
+		start := offset
+		fixed := 40 + hashSize + 2
+		if offset+fixed > len(data) {
+			return nil, errGitIndex
+		}
+		field := func(i int) uint32 {
+			return binary.BigEndian.Uint32(data[start+4*i:])
+		}
+		entry := gitIndexEntry{
+			modTime: time.Unix(int64(field(2)), int64(field(3))),
+			mode:    field(6),
+			size:    field(9),
+			hash:    data[start+40 : start+40+hashSize],
+		}
+		flags := binary.BigEndian.Uint16(data[start+40+hashSize:])
+		offset += fixed
+
+		var extended uint16
+		if version >= 3 && flags&0x4000 != 0 {
+			if offset+2 > len(data) {
+				return nil, errGitIndex
+			}
+			extended = binary.BigEndian.Uint16(data[offset:])
+			offset += 2
+		}
+
+		strip := 0
+		if version == 4 {
+			value, size := gitVarint(data[offset:])
+			if size == 0 || value < 0 || value > len(previous) {
+				return nil, errGitIndex
+			}
+			strip, offset = value, offset+size
+		}
+		end := bytes.IndexByte(data[offset:], 0)
+		if end < 0 {
+			return nil, errGitIndex
+		}
+		entry.path = previous[:len(previous)-strip] + string(data[offset:offset+end])
+		offset += end + 1
+		if version < 4 {
+			// entries are padded with NULs to a multiple of eight bytes
+			offset = start + (offset-1-start+8)&^7
+		}
+		previous = entry.path
+
+		stage := (flags >> 12) & 3
+		gitlink := entry.mode&0170000 == 0160000
+		entry.ignored = stage != 0 || gitlink || flags&0x8000 != 0 || extended&0x4000 != 0
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// gitVarint decodes the offset encoding of git's varint.c, it returns the
+// number of bytes read, 0 when data ends first, the encoding is longer than
+// 9 bytes or the value does not fit in an int.
+func gitVarint(data []byte) (int, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	value := int(data[0] & 127)
+	i := 0
+	for data[i]&128 != 0 {
+		i++
+		if i == len(data) || i == 9 || value+1 > math.MaxInt>>7 {
+			return 0, 0
+		}
+		value = ((value + 1) << 7) | int(data[i]&127)
+	}
+	return value, i + 1
+}
@@ -0,0 +1,113 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitPromptQuotesBranch(t *testing.T) {
+	repo := t.TempDir()
+	os.Mkdir(filepath.Join(repo, ".git"), 0755)
+	os.WriteFile(filepath.Join(repo, ".git", "HEAD"), []byte("ref: refs/heads/`touch pwned`$(touch pwned)\n"), 0644)
+
+	shell := New(Options{Dir: repo, Env: []string{"PS1=\\g"}})
+	if got := shell.prompt("PS1"); got != " (`touch pwned`$(touch pwned))" {
+		t.Errorf("Expected the branch name as it is, got: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "pwned")); err == nil {
+		t.Errorf("Expected the branch name not to be run")
+	}
+}
+
+func TestReadGitIndexCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	os.WriteFile(path, []byte("DIRC\x00\x00\x00\x02\xff\xff\xff\xff"), 0644)
+
+	if _, err := readGitIndex(path, false); err == nil {
+		t.Errorf("Expected an index with a corrupt entry count to be rejected")
+	}
+}
+
+func TestReadGitIndexVarint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	entry := strings.Repeat("\x00", 62) + strings.Repeat("\xff", 9) + "\x7fa\x00"
+	os.WriteFile(path, []byte("DIRC\x00\x00\x00\x04\x00\x00\x00\x01"+entry), 0644)
+
+	if _, err := readGitIndex(path, false); err == nil {
+		t.Errorf("Expected an index with an overlong path varint to be rejected")
+	}
+}
+
+func TestGitPrompt(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	os.MkdirAll(filepath.Join(repo, "sub"), 0755)
+	os.WriteFile(filepath.Join(repo, "file.txt"), []byte("one\n"), 0644)
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_AUTHOR_NAME=a", "GIT_AUTHOR_EMAIL=a@example.com", "GIT_COMMITTER_NAME=a", "GIT_COMMITTER_EMAIL=a@example.com")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	git("init", "-q", "-b", "main")
+	git("add", "file.txt")
+	git("commit", "-q", "-m", "first")
+	// the file is older than the index, so its stat data can be trusted
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(repo, "file.txt"), past, past)
+	git("update-index", "--refresh")
+
+	shell := New(Options{Dir: filepath.Join(repo, "sub"), Env: []string{"PS1=\\W\\g$ "}})
+	write := func(content string, modified time.Time) {
+		os.WriteFile(filepath.Join(repo, "file.txt"), []byte(content), 0644)
+		os.Chtimes(filepath.Join(repo, "file.txt"), modified, modified)
+	}
+
+	steps := []struct {
+		name   string
+		change func()
+		output string
+		cached bool
+	}{
+		{name: "clean", change: func() {}, output: " (main)"},
+		{name: "cached", change: func() { write("three\n", past) }, output: " (main)", cached: true},
+		{name: "other size", change: func() { write("three\n", past) }, output: " (main *)"},
+		{name: "same size", change: func() { write("two\n", past.Add(time.Minute)) }, output: " (main *)"},
+		{name: "touched only", change: func() { write("one\n", past.Add(2*time.Minute)) }, output: " (main)"},
+		{name: "executable", change: func() { os.Chmod(filepath.Join(repo, "file.txt"), 0755) }, output: " (main *)"},
+		{name: "restored", change: func() { os.Chmod(filepath.Join(repo, "file.txt"), 0644) }, output: " (main)"},
+		{name: "deleted", change: func() { os.Remove(filepath.Join(repo, "file.txt")) }, output: " (main *)"},
+		{name: "new branch", change: func() { git("checkout", "-q", "-f", "-b", "feature") }, output: " (feature)"},
+		{name: "index version 4", change: func() { git("update-index", "--index-version", "4") }, output: " (feature)"},
+		{name: "detached", change: func() { git("checkout", "-q", "--detach") }, output: " (" + git("rev-parse", "--short=7", "HEAD") + ")"},
+		{name: "outside", change: func() { shell.directory = dir }, output: ""},
+	}
+
+	for _, step := range steps {
+		step.change()
+		if !step.cached {
+			shell.git.checked = time.Time{}
+		}
+		if got := shell.gitPrompt(); got != step.output {
+			t.Errorf("%s: expected %q, got: %q", step.name, step.output, got)
+		}
+	}
+
+	shell.directory = repo
+	if got := shell.prompt("PS1"); got != "repo ("+git("rev-parse", "--short=7", "HEAD")+")$ " {
+		t.Errorf("Expected the git segment in the prompt, got: %q", got)
+	}
+}
@@ -0,0 +1,157 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type hashEntry struct {
+	path string
+	hits int
+}
+
+// commandHash remembers where commands were found in PATH so that running
+// the same command again costs one stat instead of one per PATH directory.
+type commandHash struct {
+	mu      sync.Mutex
+	entries map[string]*hashEntry
+}
+
+func (h *commandHash) lookup(name string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry, ok := h.entries[name]
+	if !ok {
+		return "", false
+	}
+	entry.hits++
+	return entry.path, true
+}
+
+func (h *commandHash) peek(name string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry, ok := h.entries[name]
+	if !ok {
+		return "", false
+	}
+	return entry.path, true
+}
+
+func (h *commandHash) set(name string, path string, hits int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.entries == nil {
+		h.entries = map[string]*hashEntry{}
+	}
+	h.entries[name] = &hashEntry{path: path, hits: hits}
+}
+
+func (h *commandHash) remove(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, ok := h.entries[name]
+	delete(h.entries, name)
+	return ok
+}
+
+func (h *commandHash) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.entries)
+}
+
+func (h *commandHash) clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.entries = nil
+}
+
+func (h *commandHash) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	names := make([]string, 0, len(h.entries))
+	for name := range h.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{"hits\tcommand"}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%4d\t%s", h.entries[name].hits, h.entries[name].path))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findCommand looks name up in the hash table first and searches PATH only
+// when it is not there or the remembered file is gone.
+func (shell *Shell) findCommand(name string) (string, bool) {
+	if path, ok := shell.hash.lookup(name); ok {
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+		shell.hash.remove(name)
+	}
+
+	ok, path := findFile(shell.workingDirectory(), shell.getenv("PATH"), name)
+	if !ok {
+		return "", false
+	}
+	shell.hash.set(name, path, 1)
+
+	return path, true
+}
+
+func (shell *Shell) handleHashCommand(args []string) (string, error) {
+	if len(args) == 0 {
+		if shell.hash.len() == 0 {
+			return "hash: hash table empty", nil
+		}
+		return shell.hash.String(), nil
+	}
+
+	switch args[0] {
+	case "-r":
+		shell.hash.clear()
+		return "", nil
+	case "-p":
+		if len(args) != 3 {
+			return "", &statusError{status: 2, message: "hash: usage: hash -p path name"}
+		}
+		shell.hash.set(args[2], shell.resolvePath(args[1]), 0)
+		return "", nil
+	case "-d":
+		if len(args) < 2 {
+			return "", &statusError{status: 2, message: "hash: usage: hash -d name ..."}
+		}
+		for _, name := range args[1:] {
+			if !shell.hash.remove(name) {
+				return "", fmt.Errorf("hash: %s: not found", name)
+			}
+		}
+		return "", nil
+	}
+
+	for _, name := range args {
+		if strings.Contains(name, "/") || shell.isBuiltinCommand(Command(name)) {
+			continue
+		}
+		path, ok := shell.findCommand(name)
+		if !ok {
+			return "", fmt.Errorf("hash: %s: not found", name)
+		}
+		shell.hash.set(name, path, 0)
+	}
+
+	return "", nil
+}
@@ -0,0 +1,77 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHashCommand(t *testing.T) {
+	directory := t.TempDir()
+	os.Mkdir(filepath.Join(directory, "first"), 0755)
+	os.Mkdir(filepath.Join(directory, "second"), 0755)
+	os.WriteFile(filepath.Join(directory, "first/tool"), []byte("#!/bin/sh\necho first\n"), 0755)
+	os.WriteFile(filepath.Join(directory, "second/tool"), []byte("#!/bin/sh\necho second\n"), 0755)
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{
+		Stdout: &output,
+		Stderr: &errout,
+		Env:    []string{"PATH=" + filepath.Join(directory, "first")},
+	})
+
+	shell.Eval("hash")
+	shell.Eval("tool")
+	shell.Eval("tool")
+	shell.Eval("hash")
+	shell.Eval("type tool")
+
+	first := filepath.Join(directory, "first/tool")
+	expected := "hash: hash table empty\nfirst\nfirst\nhits\tcommand\n   2\t" + first + "\ntool is hashed (" + first + ")\n"
+	if output.String() != expected {
+		t.Errorf("Expected result to be %q, got: %q", expected, output.String())
+	}
+
+	output.Reset()
+	shell.Eval("PATH=" + filepath.Join(directory, "second"))
+	shell.Eval("tool")
+	if output.String() != "second\n" {
+		t.Errorf("Expected assigning PATH to forget tool, got: %q", output.String())
+	}
+
+	output.Reset()
+	shell.Eval("hash -p " + first + " tool")
+	shell.Eval("tool")
+	shell.Eval("hash -d tool")
+	shell.Eval("tool")
+	shell.Eval("hash -r")
+	shell.Eval("hash")
+	if output.String() != "first\nsecond\nhash: hash table empty\n" {
+		t.Errorf("Expected -p, -d and -r to update the table, got: %q", output.String())
+	}
+
+	shell.Eval("hash -d nosuch")
+	shell.Eval("hash nosuch")
+	expected = "hash: nosuch: not found\nhash: nosuch: not found"
+	if got := strings.TrimRight(errout.String(), "\n"); got != expected {
+		t.Errorf("Expected error to be %q, got: %q", expected, got)
+	}
+}
+
+func TestHashForgetsRemovedFile(t *testing.T) {
+	directory := t.TempDir()
+	os.WriteFile(filepath.Join(directory, "tool"), []byte("#!/bin/sh\necho tool\n"), 0755)
+
+	var output bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &output, Env: []string{"PATH=" + directory}})
+
+	shell.hash.set("tool", filepath.Join(directory, "gone"), 0)
+	shell.Eval("tool")
+
+	if output.String() != "tool\n" {
+		t.Errorf("Expected stale entry to be searched again, got: %q", output.String())
+	}
+}
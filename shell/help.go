@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinHandler wraps the handler of spec so that every builtin answers
+// --help the same way, from the metadata in its spec.
+func builtinHandler(spec CommandSpec) func(shell *Shell, args []string) (string, error) {
+	return func(shell *Shell, args []string) (string, error) {
+		if len(args) > 0 && args[0] == "--help" {
+			return formatHelp(spec), nil
+		}
+		if err := shell.Context().Err(); err != nil {
+			return "", err
+		}
+
+		return spec.Handler(shell, args)
+	}
+}
+
+func usageOf(spec CommandSpec) string {
+	if spec.Usage == "" {
+		return string(spec.Name)
+	}
+	return spec.Usage
+}
+
+func formatHelp(spec CommandSpec) string {
+	var builder strings.Builder
+
+	fmt.Fprintf(&builder, "%s: %s", spec.Name, usageOf(spec))
+	if spec.Help != "" {
+		fmt.Fprintf(&builder, "\n    %s", spec.Help)
+	}
+
+	if len(spec.Flags) > 0 {
+		width := 0
+		for _, flag := range spec.Flags {
+			width = max(width, len(flagSynopsis(flag)))
+		}
+
+		builder.WriteString("\n\n    Options:")
+		for _, flag := range spec.Flags {
+			fmt.Fprintf(&builder, "\n      %-*s  %s", width, flagSynopsis(flag), flag.Help)
+		}
+	}
+
+	return builder.String()
+}
+
+func flagSynopsis(flag FlagSpec) string {
+	if flag.Arg == "" {
+		return flag.Name
+	}
+	return flag.Name + " " + flag.Arg
+}
+
+func (shell *Shell) handleHelpCommand(args []string) (string, error) {
+	registry := shell.Builtins()
+
+	if len(args) == 0 {
+		lines := []string{"Shell builtins, type `help name' for more about the builtin `name'."}
+		for _, name := range registry.Names() {
+			spec, _ := registry.Lookup(name)
+			lines = append(lines, " "+usageOf(spec))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	topics := []string{}
+	for _, name := range args {
+		spec, ok := registry.Lookup(Command(name))
+		if !ok {
+			return strings.Join(topics, "\n\n"), fmt.Errorf("help: no help topics match `%s'", name)
+		}
+		topics = append(topics, formatHelp(spec))
+	}
+
+	return strings.Join(topics, "\n\n"), nil
+}
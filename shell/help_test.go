@@ -0,0 +1,55 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHelpCommand(t *testing.T) {
+	cases := []Case{
+		{input: "help cd", output: "cd: cd dir\n    Change the current directory to dir, ~ is the home directory.", name: "help for builtin"},
+		{input: "cd --help", output: "cd: cd dir\n    Change the current directory to dir, ~ is the home directory.", name: "--help flag"},
+		{input: "help nosuch", err: "help: no help topics match `nosuch'", name: "unknown topic"},
+		{input: "hash --help", output: "hash: hash [-r] [-p path] [-d] [name ...]\n" +
+			"    Remember the full path of each name, or list the remembered commands when no name is given. Assigning PATH forgets them all.\n\n" +
+			"    Options:\n" +
+			"      -r            forget all remembered locations\n" +
+			"      -p path name  use path as the full path of name\n" +
+			"      -d name       forget the remembered location of each name", name: "flags are listed"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{}})
+
+			shell.Eval(testCase.input)
+			got := strings.TrimRight(output.String(), "\n")
+			expected := testCase.output
+			if testCase.output == "" {
+				got = strings.TrimRight(errout.String(), "\n")
+				expected = testCase.err
+			}
+
+			if got != expected {
+				t.Errorf("Expected result to be %q, got: %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestHelpListsEveryBuiltin(t *testing.T) {
+	var output bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &output, Env: []string{}})
+
+	shell.Eval("help")
+
+	for _, name := range shell.Builtins().Names() {
+		spec, _ := shell.Builtins().Lookup(name)
+		if !strings.Contains(output.String(), " "+spec.Usage+"\n") {
+			t.Errorf("Expected help to list %q, got: %q", spec.Usage, output.String())
+		}
+	}
+}
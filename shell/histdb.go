@@ -0,0 +1,321 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const histdbUsage = "histdb: usage: histdb [--cwd[=dir]] [--failed] [--since time] [--session] [-n count] [text]"
+
+// historyRecord is one command in the history database, $HISTDB, a file
+// with one JSON object per line.
+type historyRecord struct {
+	Command  string    `json:"command"`
+	Cwd      string    `json:"cwd"`
+	Start    time.Time `json:"start"`
+	Duration int64     `json:"duration_ms"`
+	Exit     int       `json:"exit"`
+	Session  string    `json:"session"`
+}
+
+// sessionID names this shell in the history database.
+func (shell *Shell) sessionID() string {
+	if shell.session == "" {
+		id := make([]byte, 8)
+		rand.Read(id)
+		shell.session = hex.EncodeToString(id)
+	}
+	return shell.session
+}
+
+// recordHistory adds a finished command to $HISTDB when it is set. The
+// database keeps the last $HISTFILESIZE records, like the history file.
+func (shell *Shell) recordHistory(line string, cwd string, start time.Time, status int) error {
+	path := shell.getenv("HISTDB")
+	if path == "" {
+		return nil
+	}
+	path = shell.resolvePath(path)
+
+	data, err := json.Marshal(historyRecord{
+		Command:  line,
+		Cwd:      cwd,
+		Start:    start,
+		Duration: time.Since(start).Milliseconds(),
+		Exit:     status,
+		Session:  shell.sessionID(),
+	})
+	if err != nil {
+		return err
+	}
+
+	unlock, err := historyLock(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := appendToFile(path, []string{string(data)}); err != nil {
+		return err
+	}
+	shell.databaseLines = append(shell.databaseLines, line)
+	return shell.truncateHistoryDatabase(path)
+}
+
+// historyCount is the number of records, one per line, in the history
+// database at path when it was offset bytes long.
+type historyCount struct {
+	path    string
+	offset  int64
+	records int
+}
+
+// countHistoryDatabase brings count up to date with the file at path. Only
+// what was appended since is read, unless the file is not the one counted
+// or got shorter, then it is counted again.
+func countHistoryDatabase(path string, count historyCount) (historyCount, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return count, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return count, err
+	}
+	if count.path != path || info.Size() < count.offset {
+		count = historyCount{path: path}
+	}
+	if _, err := file.Seek(count.offset, io.SeekStart); err != nil {
+		return count, err
+	}
+
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := file.Read(buffer)
+		count.offset += int64(n)
+		count.records += bytes.Count(buffer[:n], []byte("\n"))
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// truncateHistoryDatabase keeps the last $HISTFILESIZE lines of path, every
+// record is one line. The file is only read and rewritten once the running
+// count of its records is over the limit.
+func (shell *Shell) truncateHistoryDatabase(path string) error {
+	count, err := countHistoryDatabase(path, shell.databaseCount)
+	if err != nil {
+		return err
+	}
+	shell.databaseCount = count
+
+	size := shell.historyFileSize()
+	if size < 0 || count.records <= size {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	lines = lines[max(0, len(lines)-size):]
+	if err := WriteToFile(path, lines); err != nil {
+		return err
+	}
+
+	count = historyCount{path: path, records: len(lines)}
+	for _, line := range lines {
+		count.offset += int64(len(line)) + 1
+	}
+	shell.databaseCount = count
+	return nil
+}
+
+// readHistoryDatabase reads every record of path, lines that are not valid
+// records are skipped so one bad write does not hide the rest.
+func readHistoryDatabase(path string) ([]historyRecord, error) {
+	unlock, err := historyLock(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []historyRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var record historyRecord
+		if json.Unmarshal(scanner.Bytes(), &record) == nil && record.Command != "" {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// historyDatabaseLines are the commands of $HISTDB for the line editor,
+// oldest first and without repeats so searching finds each command once.
+func (shell *Shell) historyDatabaseLines() []string {
+	path := shell.getenv("HISTDB")
+	if path == "" {
+		return nil
+	}
+	records, err := readHistoryDatabase(shell.resolvePath(path))
+	if err != nil {
+		return nil
+	}
+
+	last := map[string]int{}
+	for i, record := range records {
+		last[record.Command] = i
+	}
+	var lines []string
+	for i, record := range records {
+		if last[record.Command] == i {
+			lines = append(lines, record.Command)
+		}
+	}
+	return lines
+}
+
+// parseSince reads the value of --since, either an age like 2h or 3d, or a
+// date with an optional time.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if since, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return since, nil
+		}
+	}
+	age, err := parseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("histdb: %s: invalid time", value)
+	}
+	return now.Add(-age), nil
+}
+
+type historyQuery struct {
+	cwd     string
+	failed  bool
+	since   time.Time
+	session string
+	count   int
+	text    string
+}
+
+func (query historyQuery) match(record historyRecord) bool {
+	return (query.cwd == "" || record.Cwd == query.cwd) &&
+		(!query.failed || record.Exit != 0) &&
+		(query.since.IsZero() || !record.Start.Before(query.since)) &&
+		(query.session == "" || record.Session == query.session) &&
+		strings.Contains(record.Command, query.text)
+}
+
+func (shell *Shell) parseHistoryQuery(args []string) (historyQuery, error) {
+	query := historyQuery{count: -1}
+	usage := func(message string) error {
+		return &statusError{status: 2, message: "histdb: " + message + "\n" + histdbUsage}
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case arg == "--cwd":
+			query.cwd = shell.workingDirectory()
+		case name == "--cwd" && hasValue:
+			query.cwd = shell.resolvePath(value)
+		case arg == "--failed":
+			query.failed = true
+		case arg == "--session":
+			query.session = shell.sessionID()
+		case name == "--since" || arg == "-n":
+			if !hasValue {
+				if i+1 == len(args) {
+					return query, usage(arg + ": option requires an argument")
+				}
+				i++
+				value = args[i]
+			}
+			if arg == "-n" {
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					return query, usage(value + ": numeric argument required")
+				}
+				query.count = n
+				continue
+			}
+			since, err := parseSince(value, time.Now())
+			if err != nil {
+				return query, err
+			}
+			query.since = since
+		case strings.HasPrefix(arg, "-"):
+			return query, usage(arg + ": invalid option")
+		default:
+			if query.text != "" {
+				return query, usage("too many arguments")
+			}
+			query.text = arg
+		}
+	}
+	return query, nil
+}
+
+// handleHistdbCommand searches $HISTDB, printing the matching commands oldest
+// first with when and where they ran and their exit status.
+func (shell *Shell) handleHistdbCommand(args []string) (string, error) {
+	path := shell.getenv("HISTDB")
+	if path == "" {
+		return "", fmt.Errorf("histdb: HISTDB not set")
+	}
+
+	query, err := shell.parseHistoryQuery(args)
+	if err != nil {
+		return "", err
+	}
+
+	records, err := readHistoryDatabase(shell.resolvePath(path))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("histdb: %v", err)
+	}
+
+	var matches []historyRecord
+	for _, record := range records {
+		if query.match(record) {
+			matches = append(matches, record)
+		}
+	}
+	if query.count >= 0 && len(matches) > query.count {
+		matches = matches[len(matches)-query.count:]
+	}
+
+	lines := make([]string, 0, len(matches))
+	for _, record := range matches {
+		lines = append(lines, fmt.Sprintf("%s  %3d  %s  %s", record.Start.Local().Format(time.DateTime), record.Exit, record.Cwd, record.Command))
+	}
+	return strings.Join(lines, "\n"), nil
+}
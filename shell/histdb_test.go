@@ -0,0 +1,165 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistdbRecordsCommands(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	histDB := filepath.Join(dir, "history.jsonl")
+
+	var output bytes.Buffer
+	shell := New(Options{
+		Stdin:  strings.NewReader("echo a\ncd sub\nls nosuchfile\n secret\n"),
+		Stdout: &output,
+		Stderr: &output,
+		Env:    []string{"PATH=" + os.Getenv("PATH"), "HISTDB=" + histDB, "HISTCONTROL=ignorespace"},
+		Dir:    dir,
+	})
+	shell.RunInteractive(context.Background())
+
+	records, err := readHistoryDatabase(histDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got: %d", len(records))
+	}
+
+	expected := []historyRecord{
+		{Command: "echo a", Cwd: dir, Exit: 0},
+		{Command: "cd sub", Cwd: dir, Exit: 0},
+		{Command: "ls nosuchfile", Cwd: filepath.Join(dir, "sub"), Exit: 2},
+	}
+	for i, record := range records {
+		if record.Command != expected[i].Command || record.Cwd != expected[i].Cwd || record.Exit != expected[i].Exit {
+			t.Errorf("Expected record %+v, got: %+v", expected[i], record)
+		}
+		if record.Session != shell.sessionID() || record.Start.IsZero() || record.Duration < 0 {
+			t.Errorf("Expected session, start and duration to be set, got: %+v", record)
+		}
+	}
+
+	lines := shell.historyDatabaseLines()
+	if !slices.Equal(lines, []string{"echo a", "cd sub", "ls nosuchfile"}) {
+		t.Errorf("Expected the commands for the line editor, got: %q", lines)
+	}
+}
+
+func TestHistdbSize(t *testing.T) {
+	histDB := filepath.Join(t.TempDir(), "history.jsonl")
+	shell := New(Options{Env: []string{"HISTDB=" + histDB, "HISTFILESIZE=2"}})
+
+	for _, line := range []string{"echo a", "echo b", "echo c"} {
+		if err := shell.recordHistory(line, "/", time.Now(), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := readHistoryDatabase(histDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Command != "echo b" || records[1].Command != "echo c" {
+		t.Errorf("Expected the last 2 records, got: %+v", records)
+	}
+}
+
+func TestHistdbSizeCountsOtherSessions(t *testing.T) {
+	histDB := filepath.Join(t.TempDir(), "history.jsonl")
+	first := New(Options{Env: []string{"HISTDB=" + histDB, "HISTFILESIZE=3"}})
+	second := New(Options{Env: []string{"HISTDB=" + histDB, "HISTFILESIZE=3"}})
+
+	for i, line := range []string{"echo a", "echo b", "echo c", "echo d", "echo e"} {
+		shell := first
+		if i%2 == 1 {
+			shell = second
+		}
+		if err := shell.recordHistory(line, "/", time.Now(), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := readHistoryDatabase(histDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].Command != "echo c" || first.databaseCount.records != 3 {
+		t.Errorf("Expected the last 3 records and a count of 3, got: %+v and %d", records, first.databaseCount.records)
+	}
+}
+
+func TestHistdbCommand(t *testing.T) {
+	dir := t.TempDir()
+	histDB := filepath.Join(dir, "history.jsonl")
+	now := time.Now()
+
+	records := []historyRecord{
+		{Command: "make", Cwd: "/src", Start: now.Add(-48 * time.Hour), Exit: 2, Session: "old"},
+		{Command: "make test", Cwd: "/src", Start: now.Add(-time.Hour), Exit: 0, Session: "old"},
+		{Command: "ls", Cwd: dir, Start: now.Add(-time.Minute), Exit: 0, Session: "old"},
+		{Command: "make test", Cwd: dir, Start: now, Exit: 1, Session: "current"},
+	}
+	var content []string
+	for _, record := range records {
+		data, _ := json.Marshal(record)
+		content = append(content, string(data))
+	}
+	content = append(content, "not json")
+	os.WriteFile(histDB, []byte(strings.Join(content, "\n")+"\n"), 0600)
+
+	format := func(record historyRecord) string {
+		return fmt.Sprintf("%s  %3d  %s  %s", record.Start.Format(time.DateTime), record.Exit, record.Cwd, record.Command)
+	}
+
+	cases := []struct {
+		name   string
+		input  string
+		output []historyRecord
+		err    string
+	}{
+		{name: "all", input: "histdb", output: records},
+		{name: "failed", input: "histdb --failed", output: []historyRecord{records[0], records[3]}},
+		{name: "cwd", input: "histdb --cwd", output: records[2:]},
+		{name: "cwd dir", input: "histdb --cwd=/src make", output: records[:2]},
+		{name: "since age", input: "histdb --since 2h", output: records[1:]},
+		{name: "since date", input: "histdb --since='" + now.Add(-2*time.Minute).Format(time.DateTime) + "'", output: records[2:]},
+		{name: "session", input: "histdb --session", output: records[3:]},
+		{name: "count", input: "histdb -n 1 make", output: records[3:]},
+		{name: "missing argument", input: "histdb --since", err: "histdb: --since: option requires an argument\n" + histdbUsage},
+		{name: "invalid option", input: "histdb --nope", err: "histdb: --nope: invalid option\n" + histdbUsage},
+		{name: "invalid time", input: "histdb --since yesterday", err: "histdb: yesterday: invalid time"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"HISTDB=" + histDB}, Dir: dir})
+			shell.session = "current"
+
+			shell.Eval(testCase.input)
+
+			if got := strings.TrimRight(errout.String(), "\n"); got != testCase.err {
+				t.Errorf("Expected error to be %q, got: %q", testCase.err, got)
+			}
+			var expected []string
+			for _, record := range testCase.output {
+				expected = append(expected, format(record))
+			}
+			if got := strings.TrimRight(output.String(), "\n"); got != strings.Join(expected, "\n") {
+				t.Errorf("Expected result to be %q, got: %q", strings.Join(expected, "\n"), got)
+			}
+		})
+	}
+}
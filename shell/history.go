@@ -0,0 +1,373 @@
+package shell
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHistorySize is used for $HISTSIZE when it is unset, like bash.
+const defaultHistorySize = 500
+
+const historyUsage = "history: usage: history [-c] [-d offset] [n] or history -anrw [filename] or history -ps arg [arg...]"
+
+// historyEntry is a command line in the history list, time is zero for lines
+// read from a file without timestamps.
+type historyEntry struct {
+	line string
+	time time.Time
+}
+
+func historyEntries(lines []string) []historyEntry {
+	entries := make([]historyEntry, 0, len(lines))
+	for _, line := range lines {
+		entries = append(entries, historyEntry{line: line})
+	}
+	return entries
+}
+
+func (shell *Shell) historyLines() []string {
+	lines := make([]string, 0, len(shell.history))
+	for _, entry := range shell.history {
+		lines = append(lines, entry.line)
+	}
+	return lines
+}
+
+// historyLimit reads a size variable, a negative or non-numeric value means
+// no limit and is returned as -1.
+func historyLimit(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// historySize is the number of entries kept in memory, $HISTSIZE.
+func (shell *Shell) historySize() int {
+	value, ok := shell.lookupEnv("HISTSIZE")
+	if !ok {
+		return defaultHistorySize
+	}
+	return historyLimit(value)
+}
+
+// historyFileSize is the number of lines kept in the history file,
+// $HISTFILESIZE, which defaults to the value of $HISTSIZE.
+func (shell *Shell) historyFileSize() int {
+	value, ok := shell.lookupEnv("HISTFILESIZE")
+	if !ok {
+		return shell.historySize()
+	}
+	return historyLimit(value)
+}
+
+// trimHistory drops the oldest entries beyond $HISTSIZE.
+func (shell *Shell) trimHistory() {
+	size := shell.historySize()
+	if size < 0 || len(shell.history) <= size {
+		return
+	}
+
+	dropped := len(shell.history) - size
+	shell.history = slices.Clone(shell.history[dropped:])
+	shell.historyWrittenIndex = max(0, shell.historyWrittenIndex-dropped)
+}
+
+// ignoreHistory reports whether $HISTCONTROL or $HISTIGNORE keep line out of
+// the history list.
+func (shell *Shell) ignoreHistory(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return true
+	}
+
+	previous := ""
+	if len(shell.history) > 0 {
+		previous = shell.history[len(shell.history)-1].line
+	}
+
+	for _, option := range strings.Split(shell.getenv("HISTCONTROL"), ":") {
+		switch option {
+		case "ignorespace":
+			if line[0] == ' ' {
+				return true
+			}
+		case "ignoredups":
+			if line == previous {
+				return true
+			}
+		case "ignoreboth":
+			if line[0] == ' ' || line == previous {
+				return true
+			}
+		}
+	}
+
+	for _, pattern := range splitHistIgnore(shell.getenv("HISTIGNORE")) {
+		// & stands for the previous history line
+		if pattern == "&" && line == previous {
+			return true
+		}
+		if pattern != "" && globMatch(pattern, line) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitHistIgnore splits $HISTIGNORE at colons, a backslash quotes a colon
+// that is part of a pattern.
+func splitHistIgnore(value string) []string {
+	var patterns []string
+	pattern := ""
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == ':':
+			pattern += ":"
+			i++
+		case value[i] == ':':
+			patterns = append(patterns, pattern)
+			pattern = ""
+		default:
+			pattern += string(value[i])
+		}
+	}
+	return append(patterns, pattern)
+}
+
+// globMatch matches the whole of s against a shell pattern. Unlike
+// path.Match, * and ? also match a slash.
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(s); i >= 0; i-- {
+				if globMatch(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 || len(s) == 0 {
+				return pattern == s
+			}
+			class := pattern[:end+2]
+			if ok, err := path.Match(class, s[:1]); err != nil || !ok {
+				return false
+			}
+			pattern, s = pattern[end+2:], s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// addHistory stores a line read from the user, it reports whether the line
+// was stored.
+func (shell *Shell) addHistory(line string) bool {
+	if shell.ignoreHistory(line) {
+		return false
+	}
+
+	if slices.Contains(strings.Split(shell.getenv("HISTCONTROL"), ":"), "erasedups") {
+		for i := len(shell.history) - 1; i >= 0; i-- {
+			if shell.history[i].line != line {
+				continue
+			}
+			shell.history = slices.Delete(shell.history, i, i+1)
+			if shell.historyWrittenIndex > i {
+				shell.historyWrittenIndex--
+			}
+		}
+	}
+
+	shell.history = append(shell.history, historyEntry{line: line, time: time.Now()})
+	shell.trimHistory()
+	return true
+}
+
+func historyUsageError(message string) error {
+	if message == "" {
+		return &statusError{status: 2, message: historyUsage}
+	}
+	return &statusError{status: 2, message: "history: " + message + "\n" + historyUsage}
+}
+
+// dropCurrentLine removes the entry of the command line being run, which -s
+// replaces with its arguments and -p must not expand against.
+func (shell *Shell) dropCurrentLine() {
+	last := len(shell.history) - 1
+	if last >= 0 && shell.currentLine != "" && shell.history[last].line == shell.currentLine {
+		shell.history = shell.history[:last]
+		shell.historyWrittenIndex = min(shell.historyWrittenIndex, len(shell.history))
+	}
+}
+
+// historyFile is the file argument of -r, -w, -a and -n, $HISTFILE when the
+// argument is left out.
+func (shell *Shell) historyFile(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", historyUsageError("too many arguments")
+	}
+	if len(args) == 1 {
+		return shell.resolvePath(args[0]), nil
+	}
+
+	histFile := shell.getenv("HISTFILE")
+	if histFile == "" {
+		return "", fmt.Errorf("history: HISTFILE not set")
+	}
+	return shell.resolvePath(histFile), nil
+}
+
+// historyOffset turns an offset as printed by history into an index, negative
+// offsets count back from the end of the list.
+func (shell *Shell) historyOffset(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("history: %s: history position out of range", value)
+	}
+
+	index := n - 1
+	if n < 0 {
+		index = len(shell.history) + n
+	}
+	if index < 0 || index >= len(shell.history) || n == 0 {
+		return 0, fmt.Errorf("history: %s: history position out of range", value)
+	}
+	return index, nil
+}
+
+func (shell *Shell) deleteHistory(value string) error {
+	if value == "" {
+		return fmt.Errorf("history: %s: history position out of range", value)
+	}
+
+	from, to := value, value
+	// a range is start-end, but -3 alone is a negative offset
+	if index := strings.Index(value[1:], "-"); index >= 0 {
+		from, to = value[:index+1], value[index+2:]
+	}
+
+	start, err := shell.historyOffset(from)
+	if err != nil {
+		return err
+	}
+	end, err := shell.historyOffset(to)
+	if err != nil {
+		return err
+	}
+	if start > end {
+		return fmt.Errorf("history: %s: history position out of range", value)
+	}
+
+	shell.history = append(shell.history[:start], shell.history[end+1:]...)
+	if shell.historyWrittenIndex > start {
+		shell.historyWrittenIndex = max(start, shell.historyWrittenIndex-(end-start+1))
+	}
+	return nil
+}
+
+func (shell *Shell) handleHistoryCommand(args []string) (string, error) {
+	limit := len(shell.history)
+	if len(args) > 0 {
+		switch args[0] {
+		case "-c":
+			if len(args) > 1 {
+				return "", historyUsageError("too many arguments")
+			}
+			shell.history = nil
+			shell.historyWrittenIndex = 0
+			return "", nil
+		case "-d":
+			if len(args) != 2 {
+				return "", historyUsageError("-d: option requires an argument")
+			}
+			return "", shell.deleteHistory(args[1])
+		case "-s":
+			shell.dropCurrentLine()
+			if len(args) > 1 {
+				shell.history = append(shell.history, historyEntry{line: strings.Join(args[1:], " "), time: time.Now()})
+				shell.trimHistory()
+			}
+			return "", nil
+		case "-p":
+			shell.dropCurrentLine()
+			lines := []string{}
+			for _, arg := range args[1:] {
+				expansion, err := shell.expandHistory(arg)
+				if err != nil {
+					return strings.Join(lines, "\n"), fmt.Errorf("history: %v", err)
+				}
+				lines = append(lines, expansion.line)
+			}
+			return strings.Join(lines, "\n"), nil
+		case "-r", "-n":
+			filepath, err := shell.historyFile(args[1:])
+			if err != nil {
+				return "", err
+			}
+			return "", shell.readHistory(filepath, args[0] == "-n")
+		case "-w":
+			filepath, err := shell.historyFile(args[1:])
+			if err != nil {
+				return "", err
+			}
+			return "", shell.writeHistory(filepath)
+		case "-a":
+			filepath, err := shell.historyFile(args[1:])
+			if err != nil {
+				return "", err
+			}
+			return "", shell.appendHistory(filepath)
+		default:
+			if len(args) > 1 {
+				return "", historyUsageError("too many arguments")
+			}
+			if strings.HasPrefix(args[0], "-") && len(args[0]) > 1 && !isDigit(args[0][1]) {
+				return "", historyUsageError(args[0] + ": invalid option")
+			}
+
+			num, err := strconv.Atoi(args[0])
+			if err != nil || num < 0 {
+				return "", historyUsageError(args[0] + ": numeric argument required")
+			}
+
+			limit = min(num, len(shell.history))
+		}
+	}
+	output := ""
+	timeFormat := shell.getenv("HISTTIMEFORMAT")
+
+	for i := len(shell.history) - limit; i < len(shell.history); i++ {
+		entry := shell.history[i]
+		stamp := ""
+		if timeFormat != "" {
+			stamp = "??"
+			if !entry.time.IsZero() {
+				stamp = strftime(timeFormat, entry.time)
+			}
+		}
+		output += fmt.Sprintf("%5d  %s%v\n", i+1, stamp, entry.line)
+	}
+	return strings.TrimRight(output, "\n"), nil
+}
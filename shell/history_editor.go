@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/chzyer/readline"
+)
+
+// editorHistory is what the line editor searches: the commands of earlier
+// sessions from $HISTDB that are not in the history list, then the list.
+func (shell *Shell) editorHistory() []string {
+	lines := shell.historyLines()
+
+	seen := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		seen[line] = true
+	}
+	var older []string
+	for _, line := range shell.databaseLines {
+		if !seen[line] {
+			seen[line] = true
+			older = append(older, line)
+		}
+	}
+	return append(older, lines...)
+}
+
+// loadEditorHistory replaces the history of the line editor before a prompt,
+// so entries added by history -r, -n or another session are there at once.
+// The editor only reads keys while a line is being read, so nothing else
+// touches its history here.
+func (shell *Shell) loadEditorHistory(l *readline.Instance, navigator *historyNavigator) {
+	lines := shell.editorHistory()
+
+	l.ResetHistory()
+	for _, line := range lines {
+		l.SaveHistory(line)
+	}
+	navigator.reset(lines)
+}
+
+// historyNavigator is the readline listener behind the up and down arrows.
+// They step through the entries that start with the text before the cursor,
+// so typing git and pressing up finds the last git command, and with an
+// empty line they step through every entry. Ctrl-R is left to readline.
+type historyNavigator struct {
+	mu    sync.Mutex
+	lines []string
+
+	// line and pos are the state after the last key, the prefix is taken
+	// from them when navigation starts because readline has already moved
+	// through its own history by the time the listener sees the arrow.
+	line []rune
+	pos  int
+
+	navigating bool
+	prefix     string
+	typed      []rune
+	index      int
+}
+
+func (n *historyNavigator) reset(lines []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.lines = lines
+	n.line, n.pos = nil, 0
+	n.navigating = false
+}
+
+func (n *historyNavigator) OnChange(line []rune, pos int, key rune) ([]rune, int, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if key != readline.CharPrev && key != readline.CharNext {
+		n.navigating = false
+		n.line, n.pos = slices.Clone(line), pos
+		return nil, 0, false
+	}
+
+	if !n.navigating {
+		n.navigating = true
+		n.prefix = string(n.line[:min(n.pos, len(n.line))])
+		n.typed = n.line
+		n.index = len(n.lines)
+	}
+
+	shown := string(n.line)
+	found := n.index
+	if key == readline.CharPrev {
+		for i := n.index - 1; i >= 0; i-- {
+			if strings.HasPrefix(n.lines[i], n.prefix) && n.lines[i] != shown {
+				found = i
+				break
+			}
+		}
+	} else {
+		found = len(n.lines)
+		for i := n.index + 1; i < len(n.lines); i++ {
+			if strings.HasPrefix(n.lines[i], n.prefix) && n.lines[i] != shown {
+				found = i
+				break
+			}
+		}
+	}
+
+	n.index = found
+	next := n.typed
+	if found < len(n.lines) {
+		next = []rune(n.lines[found])
+	}
+	n.line, n.pos = slices.Clone(next), len(next)
+	return next, len(next), true
+}
@@ -0,0 +1,112 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chzyer/readline"
+)
+
+func TestHistoryNavigator(t *testing.T) {
+	navigator := &historyNavigator{}
+	navigator.reset([]string{"git status", "ls", "git commit", "make"})
+
+	typed := []rune("git")
+	navigator.OnChange(nil, 0, 0)
+	navigator.OnChange(typed, len(typed), 't')
+
+	keys := []struct {
+		key  rune
+		line string
+	}{
+		{readline.CharPrev, "git commit"},
+		{readline.CharPrev, "git status"},
+		{readline.CharPrev, "git status"},
+		{readline.CharNext, "git commit"},
+		{readline.CharNext, "git"},
+	}
+	for _, step := range keys {
+		// readline moves through its own history before calling the listener
+		line, pos, ok := navigator.OnChange([]rune("whatever readline shows"), 0, step.key)
+		if !ok || string(line) != step.line || pos != len(line) {
+			t.Errorf("Expected %q, got: %q %d %t", step.line, string(line), pos, ok)
+		}
+	}
+
+	navigator.OnChange(nil, 0, 'x')
+	line, _, _ := navigator.OnChange(nil, 0, readline.CharPrev)
+	if string(line) != "make" {
+		t.Errorf("Expected an empty line to step through every entry, got: %q", string(line))
+	}
+}
+
+func TestHistoryInLineEditor(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(histFile, []byte("echo fromfile\n"), 0600)
+
+	up := "\x1b[A"
+	cases := []Case{
+		{input: "echo one\necho two\n" + up + up + "\n", output: "one\ntwo\none", name: "up arrow"},
+		{input: "echo one\necho two\n" + up + up + "\x1b[B\n", output: "one\ntwo\ntwo", name: "down arrow"},
+		{input: "echo one\necho two\necho o" + up + "\n", output: "one\ntwo\none", name: "prefix search"},
+		{input: "history -r " + histFile + "\n" + up + up + "\n", output: "fromfile", name: "history -r"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			shell := Shell{
+				in:     strings.NewReader(testCase.input),
+				stdout: &output,
+				stderr: &output,
+				env:    map[string]string{},
+			}
+
+			shell.startCli()
+
+			if got := getRawOutput(output.String()); got != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, got)
+			}
+		})
+	}
+}
+
+func TestReverseSearchInLineEditor(t *testing.T) {
+	var output bytes.Buffer
+	shell := Shell{
+		in:     strings.NewReader("echo one\necho two\n\x12one\n"),
+		stdout: &output,
+		stderr: &output,
+		env:    map[string]string{},
+	}
+
+	shell.startCli()
+
+	expected := []string{"echo one", "echo two", "echo one"}
+	if !slices.Equal(shell.historyLines(), expected) {
+		t.Errorf("Expected history %q, got: %q", expected, shell.historyLines())
+	}
+}
+
+func TestEditorHistoryFromDatabase(t *testing.T) {
+	histDB := filepath.Join(t.TempDir(), "history.jsonl")
+	os.WriteFile(histDB, []byte(`{"command":"echo old"}`+"\n"+`{"command":"echo new"}`+"\n"), 0600)
+
+	var output bytes.Buffer
+	shell := New(Options{
+		Stdin:  strings.NewReader("echo new\necho again\n"),
+		Stdout: &output,
+		Stderr: &output,
+		Env:    []string{"HISTDB=" + histDB},
+	})
+	shell.startCli()
+
+	expected := []string{"echo old", "echo new", "echo again"}
+	if got := shell.editorHistory(); !slices.Equal(got, expected) {
+		t.Errorf("Expected editor history %q, got: %q", expected, got)
+	}
+}
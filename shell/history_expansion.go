@@ -0,0 +1,398 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// historyExpansion is the result of expanding the csh-style history
+// references in a line: !!, !n, !-n, !prefix, !?str? and ^old^new.
+type historyExpansion struct {
+	line      string
+	expanded  bool
+	printOnly bool
+}
+
+type substitution struct {
+	old string
+	new string
+}
+
+// historyWordBreaks end a !prefix event and never start one when they follow
+// the !.
+const historyWordBreaks = " \t\n;&|<>()'\"`:"
+
+func (shell *Shell) expandHistory(line string) (historyExpansion, error) {
+	result := historyExpansion{line: line}
+
+	if strings.HasPrefix(line, "^") {
+		return shell.quickSubstitution(line)
+	}
+
+	var builder strings.Builder
+	inSingleQuote := false
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+
+		switch {
+		case char == '\\' && i+1 < len(line):
+			builder.WriteByte(char)
+			builder.WriteByte(line[i+1])
+			i++
+			continue
+		case char == '\'':
+			inSingleQuote = !inSingleQuote
+		case char == '!' && !inSingleQuote && i+1 < len(line) && !strings.ContainsRune(" \t\n=(\"", rune(line[i+1])):
+			text, end, printOnly, err := shell.expandEvent(line, i, builder.String())
+			if err != nil {
+				return result, err
+			}
+			builder.WriteString(text)
+			result.expanded = true
+			result.printOnly = result.printOnly || printOnly
+			i = end - 1
+			continue
+		}
+
+		builder.WriteByte(char)
+	}
+
+	result.line = builder.String()
+	return result, nil
+}
+
+// quickSubstitution handles ^old^new^, which is !!:s/old/new/.
+func (shell *Shell) quickSubstitution(line string) (historyExpansion, error) {
+	result := historyExpansion{line: line}
+	if len(shell.history) == 0 {
+		return result, fmt.Errorf("%s: event not found", line)
+	}
+
+	parts := strings.SplitN(line[1:], "^", 3)
+	if len(parts) < 2 {
+		parts = append(parts, "")
+	}
+	old, new := parts[0], parts[1]
+	rest := ""
+	if len(parts) == 3 {
+		rest = parts[2]
+	}
+
+	previous := shell.history[len(shell.history)-1].line
+	if old == "" || !strings.Contains(previous, old) {
+		return result, fmt.Errorf(":s^%s^%s^: substitution failed", old, new)
+	}
+
+	shell.lastSubstitution = &substitution{old: old, new: new}
+	result.line = strings.Replace(previous, old, new, 1) + rest
+	result.expanded = true
+
+	return result, nil
+}
+
+// expandEvent expands the reference starting with the ! at start. current is
+// the line expanded so far, which !# refers to. It returns the text, the index
+// just after the reference and whether :p asked to print without running.
+func (shell *Shell) expandEvent(line string, start int, current string) (string, int, bool, error) {
+	i := start + 1
+	event := ""
+	found := true
+
+	switch {
+	case line[i] == '!':
+		found = len(shell.history) > 0
+		if found {
+			event = shell.history[len(shell.history)-1].line
+		}
+		i++
+	case line[i] == '#':
+		event = current
+		i++
+	case isDigit(line[i]) || (line[i] == '-' && i+1 < len(line) && isDigit(line[i+1])):
+		end := i + 1
+		for end < len(line) && isDigit(line[end]) {
+			end++
+		}
+		n, _ := strconv.Atoi(line[i:end])
+		index := n - 1
+		if n < 0 {
+			index = len(shell.history) + n
+		}
+		found = index >= 0 && index < len(shell.history)
+		if found {
+			event = shell.history[index].line
+		}
+		i = end
+	case line[i] == '?':
+		end := strings.IndexByte(line[i+1:], '?')
+		search := line[i+1:]
+		i = len(line)
+		if end >= 0 {
+			search = line[start+2 : start+2+end]
+			i = start + 2 + end + 1
+		}
+		event, found = shell.searchHistory(func(entry string) bool {
+			return strings.Contains(entry, search)
+		})
+	case strings.IndexByte("^$*:", line[i]) >= 0:
+		found = len(shell.history) > 0
+		if found {
+			event = shell.history[len(shell.history)-1].line
+		}
+	default:
+		end := i
+		for end < len(line) && !strings.ContainsRune(historyWordBreaks, rune(line[end])) {
+			end++
+		}
+		prefix := line[i:end]
+		event, found = shell.searchHistory(func(entry string) bool {
+			return strings.HasPrefix(entry, prefix)
+		})
+		i = end
+	}
+
+	if !found {
+		return "", i, false, fmt.Errorf("%s: event not found", line[start:i])
+	}
+
+	text, i, err := selectWords(event, line, i)
+	if err != nil {
+		return "", i, false, fmt.Errorf("%s: %v", line[start:i], err)
+	}
+
+	return shell.applyModifiers(text, line, i)
+}
+
+func (shell *Shell) searchHistory(match func(entry string) bool) (string, bool) {
+	for i := len(shell.history) - 1; i >= 0; i-- {
+		if match(shell.history[i].line) {
+			return shell.history[i].line, true
+		}
+	}
+	return "", false
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+// historyWords splits an entry into words on blanks outside of quotes, the
+// way word designators count them: word 0 is the command.
+func historyWords(entry string) []string {
+	words := []string{}
+	var word strings.Builder
+	quote := byte(0)
+	inWord := false
+
+	for i := 0; i < len(entry); i++ {
+		char := entry[i]
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == '\\' && i+1 < len(entry):
+			word.WriteByte(char)
+			i++
+			char = entry[i]
+		case char == ' ' || char == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+			continue
+		}
+		word.WriteByte(char)
+		inWord = true
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words
+}
+
+// selectWords applies the word designator at line[i:], if there is one:
+// :n, :x-y, :x-, :x*, ^, $, * and -y, with or without the leading colon.
+func selectWords(event string, line string, i int) (string, int, error) {
+	if i >= len(line) {
+		return event, i, nil
+	}
+
+	j := i
+	if line[j] == ':' {
+		j++
+		if j >= len(line) || strings.IndexByte("0123456789^$*-", line[j]) < 0 {
+			return event, i, nil
+		}
+	} else if strings.IndexByte("^$*", line[j]) < 0 {
+		return event, i, nil
+	}
+
+	words := historyWords(event)
+	last := len(words) - 1
+
+	readIndex := func() (int, bool) {
+		switch {
+		case j < len(line) && line[j] == '^':
+			j++
+			return 1, true
+		case j < len(line) && line[j] == '$':
+			j++
+			return last, true
+		case j < len(line) && isDigit(line[j]):
+			end := j
+			for end < len(line) && isDigit(line[end]) {
+				end++
+			}
+			n, _ := strconv.Atoi(line[j:end])
+			j = end
+			return n, true
+		}
+		return 0, false
+	}
+
+	from, to := 0, 0
+	if line[j] == '*' {
+		j++
+		if last < 1 {
+			return "", j, nil
+		}
+		from, to = 1, last
+	} else {
+		x, ok := readIndex()
+		if !ok {
+			x = 0
+		}
+		from, to = x, x
+		if j < len(line) && line[j] == '*' {
+			j++
+			to = last
+		} else if j < len(line) && line[j] == '-' {
+			j++
+			y, ok := readIndex()
+			if !ok {
+				y = last - 1
+			}
+			to = y
+		}
+	}
+
+	if from < 0 || to > last || from > to {
+		return "", j, fmt.Errorf("bad word specifier")
+	}
+
+	return strings.Join(words[from:to+1], " "), j, nil
+}
+
+// applyModifiers applies :h, :t, :r, :e, :p, :& and :s/old/new/ (with an
+// optional g) to text.
+func (shell *Shell) applyModifiers(text string, line string, i int) (string, int, bool, error) {
+	printOnly := false
+
+	for i+1 < len(line) && line[i] == ':' {
+		global := false
+		j := i + 1
+		if line[j] == 'g' && j+1 < len(line) && (line[j+1] == 's' || line[j+1] == '&') {
+			global = true
+			j++
+		}
+
+		switch line[j] {
+		case 'h':
+			if index := strings.LastIndexByte(text, '/'); index > 0 {
+				text = text[:index]
+			}
+		case 't':
+			if index := strings.LastIndexByte(text, '/'); index >= 0 {
+				text = text[index+1:]
+			}
+		case 'r':
+			if index := strings.LastIndexByte(text, '.'); index > strings.LastIndexByte(text, '/') {
+				text = text[:index]
+			}
+		case 'e':
+			if index := strings.LastIndexByte(text, '.'); index > strings.LastIndexByte(text, '/') {
+				text = text[index:]
+			}
+		case 'p':
+			printOnly = true
+		case '&':
+			if shell.lastSubstitution == nil {
+				return "", j, false, fmt.Errorf("no previous substitution")
+			}
+			text = substitute(text, *shell.lastSubstitution, global)
+		case 's':
+			sub, end, err := shell.parseSubstitution(line, j+1)
+			if err != nil {
+				return "", end, false, err
+			}
+			if !strings.Contains(text, sub.old) {
+				return "", end, false, fmt.Errorf("%s: substitution failed", line[i:end])
+			}
+			shell.lastSubstitution = &sub
+			text = substitute(text, sub, global)
+			j = end - 1
+		default:
+			return text, i, printOnly, nil
+		}
+		i = j + 1
+	}
+
+	return text, i, printOnly, nil
+}
+
+// parseSubstitution reads /old/new/ starting at the delimiter line[i]. The
+// last delimiter may be left out at the end of the line, an empty old reuses
+// the previous one and & in new stands for old.
+func (shell *Shell) parseSubstitution(line string, i int) (substitution, int, error) {
+	if i >= len(line) {
+		return substitution{}, i, fmt.Errorf("bad substitution")
+	}
+	delimiter := line[i]
+	parts := []string{}
+	start := i + 1
+	end := len(line)
+
+	for j := start; j < len(line) && len(parts) < 2; j++ {
+		if line[j] == '\\' && j+1 < len(line) && line[j+1] == delimiter {
+			j++
+			continue
+		}
+		if line[j] == delimiter {
+			parts = append(parts, strings.ReplaceAll(line[start:j], "\\"+string(delimiter), string(delimiter)))
+			start = j + 1
+			end = j + 1
+		}
+	}
+	if len(parts) == 0 {
+		return substitution{}, len(line), fmt.Errorf("bad substitution")
+	}
+	if len(parts) == 1 {
+		parts = append(parts, line[start:])
+		end = len(line)
+	}
+
+	sub := substitution{old: parts[0], new: parts[1]}
+	if sub.old == "" {
+		if shell.lastSubstitution == nil {
+			return sub, end, fmt.Errorf("no previous substitution")
+		}
+		sub.old = shell.lastSubstitution.old
+	}
+	sub.new = strings.ReplaceAll(sub.new, "&", sub.old)
+
+	return sub, end, nil
+}
+
+func substitute(text string, sub substitution, global bool) string {
+	if global {
+		return strings.ReplaceAll(text, sub.old, sub.new)
+	}
+	return strings.Replace(text, sub.old, sub.new, 1)
+}
@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExpandHistory(t *testing.T) {
+	history := []string{
+		"cd /tmp/project",
+		"ls -l src/main.go docs/readme.md",
+		"echo 'a b' c",
+		"git commit -m fix",
+	}
+
+	cases := []struct {
+		input     string
+		output    string
+		err       string
+		printOnly bool
+	}{
+		{input: "!!", output: "git commit -m fix"},
+		{input: "!-2", output: "echo 'a b' c"},
+		{input: "!?readme?", output: "ls -l src/main.go docs/readme.md"},
+		{input: "echo !$", output: "echo fix"},
+		{input: "echo !ls:1-2", output: "echo -l src/main.go"},
+		{input: "cat !ls:2:h", output: "cat src"},
+		{input: "!ls:gs/m/M", output: "ls -l src/Main.go docs/readMe.Md"},
+		{input: "!!:p", output: "git commit -m fix", printOnly: true},
+		{input: "^fix^feature", output: "git commit -m feature"},
+		{input: "echo '!!' \\!!", output: "echo '!!' \\!!"},
+		{input: "!nosuch", err: "!nosuch: event not found"},
+		{input: "!!:9", err: "!!:9: bad word specifier"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.input, func(t *testing.T) {
+			shell := Shell{history: historyEntries(history)}
+
+			got, err := shell.expandHistory(testCase.input)
+			if testCase.err != "" {
+				if err == nil || err.Error() != testCase.err {
+					t.Errorf("Expected error %q, got: %v", testCase.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.line != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, got.line)
+			}
+			if got.printOnly != testCase.printOnly {
+				t.Errorf("Expected printOnly to be %t", testCase.printOnly)
+			}
+		})
+	}
+}
+
+func TestHistoryExpansionIsEchoedAndStored(t *testing.T) {
+	input := strings.NewReader("echo one\necho !$ two\n!!:p\n")
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := Shell{
+		in:     input,
+		stdout: &output,
+		stderr: &errout,
+	}
+
+	shell.startCli()
+	got := getRawOutput(output.String())
+	expected := "one\necho one two\none two\necho one two"
+
+	if got != expected {
+		t.Errorf("Expected result to be %q, got: %q", expected, got)
+	}
+
+	expectedHistory := []string{"echo one", "echo one two", "echo one two"}
+	if strings.Join(shell.historyLines(), "\n") != strings.Join(expectedHistory, "\n") {
+		t.Errorf("Expected history %q, got: %q", expectedHistory, shell.historyLines())
+	}
+}
@@ -0,0 +1,384 @@
+package shell
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func readFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	bufio := bufio.NewScanner(file)
+	output := []string{}
+	for {
+		ok := bufio.Scan()
+		if !ok {
+			break
+		}
+		line := bufio.Text()
+		output = append(output, line)
+	}
+
+	return output, bufio.Err()
+}
+
+func WriteToFile(path string, data []string) error {
+	dataToWrite := ""
+	if len(data) > 0 {
+		dataToWrite = strings.Join(data, "\n") + "\n"
+	}
+	err := os.WriteFile(path, []byte(dataToWrite), 0600)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func appendToFile(path string, data []string) error {
+	if len(data) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	if _, err := f.WriteString(strings.Join(data, "\n") + "\n"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// historyTimestamp parses a #<epoch> line, which bash writes before every
+// entry of the history file.
+func historyTimestamp(line string) (time.Time, bool) {
+	if len(line) < 2 || line[0] != '#' {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(line[1:], 10, 64)
+	if err != nil || !isDigit(line[1]) {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// parseHistory turns the lines of a history file into entries. Once the file
+// has timestamps, every line up to the next timestamp belongs to the same
+// entry, that is how multi-line commands are stored.
+func parseHistory(lines []string) []historyEntry {
+	var entries []historyEntry
+	timed, pending := false, false
+	for _, line := range lines {
+		if stamp, ok := historyTimestamp(line); ok {
+			entries = append(entries, historyEntry{time: stamp})
+			timed, pending = true, true
+			continue
+		}
+
+		switch {
+		case pending:
+			entries[len(entries)-1].line = line
+			pending = false
+		case timed:
+			entries[len(entries)-1].line += "\n" + line
+		default:
+			entries = append(entries, historyEntry{line: line})
+		}
+	}
+	if pending {
+		entries = entries[:len(entries)-1]
+	}
+	return entries
+}
+
+// formatHistory is the inverse of parseHistory.
+func formatHistory(entries []historyEntry) []string {
+	lines := make([]string, 0, 2*len(entries))
+	for _, entry := range entries {
+		if !entry.time.IsZero() {
+			lines = append(lines, "#"+strconv.FormatInt(entry.time.Unix(), 10))
+		}
+		lines = append(lines, entry.line)
+	}
+	return lines
+}
+
+func readHistoryFile(path string) ([]historyEntry, error) {
+	lines, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseHistory(lines), nil
+}
+
+func writeHistoryFile(path string, entries []historyEntry) error {
+	return WriteToFile(path, formatHistory(entries))
+}
+
+// truncateHistoryFile keeps the last $HISTFILESIZE lines of path, like bash
+// the timestamp lines count too. The lines of an entry whose timestamp would
+// be cut off are dropped with it.
+func (shell *Shell) truncateHistoryFile(path string) error {
+	size := shell.historyFileSize()
+	if size < 0 {
+		return nil
+	}
+
+	lines, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = shell.truncateHistoryLines(path, lines, size)
+	return err
+}
+
+// truncateHistoryLines is truncateHistoryFile for the lines the file at path
+// has, it returns the ones left in it.
+func (shell *Shell) truncateHistoryLines(path string, lines []string, size int) ([]string, error) {
+	if len(lines) <= size {
+		return lines, nil
+	}
+	start := len(lines) - size
+	if slices.ContainsFunc(lines, func(line string) bool {
+		_, ok := historyTimestamp(line)
+		return ok
+	}) {
+		for start < len(lines) {
+			if _, ok := historyTimestamp(lines[start]); ok {
+				break
+			}
+			start++
+		}
+	}
+
+	dropped := len(parseHistory(lines)) - len(parseHistory(lines[start:]))
+	shell.historyReadEntries = max(0, shell.historyReadEntries-dropped)
+	return lines[start:], WriteToFile(path, lines[start:])
+}
+
+// historyPosition is how far the history file at path has been read: offset
+// bytes, which are lines lines and end with last. When the file does not
+// have last there any more, another session rewrote it.
+type historyPosition struct {
+	path   string
+	offset int64
+	lines  int
+	last   string
+}
+
+// positionAfter is the position at the end of a file with lines.
+func positionAfter(path string, lines []string) historyPosition {
+	position := historyPosition{path: path, lines: len(lines)}
+	for _, line := range lines {
+		position.offset += int64(len(line)) + 1
+	}
+	if len(lines) > 0 {
+		position.last = lines[len(lines)-1]
+	}
+	return position
+}
+
+// readAppendedLines returns the lines added to path after position, ok is
+// false when position is not one in this file and it has to be read again.
+func readAppendedLines(path string, position historyPosition) (lines []string, ok bool, err error) {
+	start := position.offset - int64(len(position.last)) - 1
+	if position.path != path || position.offset == 0 || start < 0 {
+		return nil, false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return nil, false, err
+	}
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() || scanner.Text() != position.last {
+		return nil, false, scanner.Err()
+	}
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, true, scanner.Err()
+}
+
+// historyLock opens path and locks it, shared for reading and exclusive for
+// writing, so sessions using the same file never see half of a write.
+func historyLock(path string, exclusive bool) (func(), error) {
+	flag := os.O_RDONLY
+	if exclusive {
+		flag = os.O_RDWR | os.O_CREATE
+	}
+	file, err := os.OpenFile(path, flag, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file, exclusive); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+	}, nil
+}
+
+// mergeHistory adds entries that are already in the history file. They go
+// before the entries of this session that were not written yet, so those
+// stay at the end of the list for the next append.
+func (shell *Shell) mergeHistory(entries []historyEntry) {
+	unwritten := slices.Clone(shell.history[shell.historyWrittenIndex:])
+	shell.history = append(append(shell.history[:shell.historyWrittenIndex], entries...), unwritten...)
+	shell.historyWrittenIndex += len(entries)
+	shell.trimHistory()
+}
+
+// readHistory is history -r, or history -n when onlyNew is set, which reads
+// only the entries added to the file since it was last read.
+func (shell *Shell) readHistory(path string, onlyNew bool) error {
+	// the file is read or written without $HISTSHARE keeping track
+	shell.historyPosition = historyPosition{}
+	unlock, err := historyLock(path, false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := readHistoryFile(path)
+	if err != nil {
+		return err
+	}
+	read := entries
+	if onlyNew {
+		read = entries[min(shell.historyReadEntries, len(entries)):]
+	}
+
+	shell.mergeHistory(read)
+	shell.historyReadEntries = len(entries)
+	return nil
+}
+
+// writeHistory is history -w, it replaces the file with the history list.
+func (shell *Shell) writeHistory(path string) error {
+	// the file is read or written without $HISTSHARE keeping track
+	shell.historyPosition = historyPosition{}
+	unlock, err := historyLock(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := writeHistoryFile(path, shell.history); err != nil {
+		return err
+	}
+	shell.historyWrittenIndex = len(shell.history)
+	shell.historyReadEntries = len(shell.history)
+	return shell.truncateHistoryFile(path)
+}
+
+// appendHistory is history -a, it adds the entries of this session that are
+// not in the file yet. The shell does it on exit, so several sessions
+// closing in any order keep each other's commands.
+func (shell *Shell) appendHistory(path string) error {
+	// the file is read or written without $HISTSHARE keeping track
+	shell.historyPosition = historyPosition{}
+	unlock, err := historyLock(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := readHistoryFile(path)
+	if err != nil {
+		return err
+	}
+
+	unwritten := shell.history[shell.historyWrittenIndex:]
+	if err := appendToFile(path, formatHistory(unwritten)); err != nil {
+		return err
+	}
+	// lines other sessions appended are still unread, -n must not skip them
+	if shell.historyReadEntries == len(entries) {
+		shell.historyReadEntries += len(unwritten)
+	}
+	shell.historyWrittenIndex = len(shell.history)
+	return shell.truncateHistoryFile(path)
+}
+
+// shareHistory appends the new entries of this session and reads the ones
+// other sessions appended, in one locked step. It runs before every prompt
+// when $HISTSHARE is set, so it reads only what was added since the last
+// time and rewrites the file only when it is over $HISTFILESIZE.
+func (shell *Shell) shareHistory(path string) error {
+	unlock, err := historyLock(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	position := shell.historyPosition
+	added, ok, err := readAppendedLines(path, position)
+	if err != nil {
+		return err
+	}
+	read := parseHistory(added)
+	entries := shell.historyReadEntries + len(read)
+	if !ok {
+		lines, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		all := parseHistory(lines)
+		read = all[min(shell.historyReadEntries, len(all)):]
+		entries = len(all)
+		position, added = historyPosition{path: path}, lines
+	}
+
+	unwritten := shell.history[shell.historyWrittenIndex:]
+	written := formatHistory(unwritten)
+	if err := appendToFile(path, written); err != nil {
+		return err
+	}
+	shell.mergeHistory(read)
+	shell.historyWrittenIndex = len(shell.history)
+	shell.historyReadEntries = entries + len(unwritten)
+
+	added = append(added, written...)
+	position.lines += len(added)
+	if len(added) > 0 {
+		position.last = added[len(added)-1]
+	}
+	if info, err := os.Stat(path); err == nil {
+		position.offset = info.Size()
+	}
+	shell.historyPosition = position
+
+	if size := shell.historyFileSize(); size >= 0 && position.lines > size {
+		lines, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		kept, err := shell.truncateHistoryLines(path, lines, size)
+		if err != nil {
+			return err
+		}
+		shell.historyPosition = positionAfter(path, kept)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// runHistoryLines feeds input to the read loop of a shell that has history
+// already, and returns what it printed and its errors.
+func runHistoryLines(env map[string]string, history []string, input string) (string, string) {
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := Shell{
+		in:      strings.NewReader(input + "\n"),
+		stdout:  &output,
+		stderr:  &errout,
+		env:     env,
+		history: historyEntries(history),
+	}
+
+	shell.startCli()
+
+	return getRawOutput(output.String()), strings.TrimRight(errout.String(), "\n")
+}
+
+func TestHistoryCommand(t *testing.T) {
+	history := []string{"echo a", "echo b", "echo c"}
+
+	if output, _ := runHistoryLines(map[string]string{}, history, "history 2"); output != "3  echo c\n    4  history 2" {
+		t.Errorf("Expected the last two entries, got: %q", output)
+	}
+	if output, _ := runHistoryLines(map[string]string{}, history, "history -c\nhistory"); output != "1  history" {
+		t.Errorf("Expected -c to clear the history, got: %q", output)
+	}
+	if _, errout := runHistoryLines(map[string]string{}, history, "history -d ''"); errout != "history: : history position out of range" {
+		t.Errorf("Expected an empty offset to be rejected, got: %q", errout)
+	}
+}
+
+func TestHistoryReadNewLines(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(histFile, []byte("echo one\n"), 0644)
+
+	var output bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &output, Env: []string{"HISTFILE=" + histFile}})
+
+	shell.Eval("history -r")
+	appendToFile(histFile, []string{"echo two", "echo three"})
+	shell.Eval("history -n")
+	shell.Eval("history -n")
+
+	expected := []string{"echo one", "echo two", "echo three"}
+	if strings.Join(shell.historyLines(), "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected history %q, got: %q", expected, shell.historyLines())
+	}
+}
+
+func TestHistoryControl(t *testing.T) {
+	env := map[string]string{"HISTCONTROL": "ignorespace:ignoredups"}
+	output, _ := runHistoryLines(env, nil, " echo secret\necho a\necho a\nhistory")
+
+	if index := strings.Index(output, "    1  "); index < 0 || output[index:] != "    1  echo a\n    2  history" {
+		t.Errorf("Expected the spaced line and the duplicate to be left out, got: %q", output)
+	}
+}
+
+func TestHistoryFileSize(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+
+	var output bytes.Buffer
+	shell := New(Options{
+		Stdin:  strings.NewReader("echo a\necho b\necho c\nexit\n"),
+		Stdout: &output,
+		Stderr: &output,
+		Env:    []string{"HISTFILE=" + histFile, "HISTFILESIZE=5"},
+	})
+	shell.RunInteractive(context.Background())
+
+	// the last five lines start in the middle of the entry of echo b
+	lines, err := readFile(histFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 4 {
+		t.Errorf("Expected 4 lines in the file, got: %q", lines)
+	}
+	entries, err := readHistoryFile(histFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shell.history = entries
+	expected := []string{"echo c", "exit"}
+	if !slices.Equal(shell.historyLines(), expected) {
+		t.Errorf("Expected file %q, got: %q", expected, shell.historyLines())
+	}
+}
+
+func TestHistoryTimestamps(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+	content := "echo plain\n#1709622489\nfor x in a b\ndo echo $x\ndone\n#1709622490\necho two\n"
+	os.WriteFile(histFile, []byte(content), 0644)
+
+	var output bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &output, Env: []string{"HISTFILE=" + histFile, "HISTTIMEFORMAT=%s "}})
+
+	shell.Eval("history -r")
+	expected := []string{"echo plain", "for x in a b\ndo echo $x\ndone", "echo two"}
+	if !slices.Equal(shell.historyLines(), expected) {
+		t.Errorf("Expected history %q, got: %q", expected, shell.historyLines())
+	}
+
+	shell.Eval("history")
+	expectedOutput := "    1  ??echo plain\n    2  1709622489 for x in a b\ndo echo $x\ndone\n    3  1709622490 echo two\n"
+	if output.String() != expectedOutput {
+		t.Errorf("Expected result to be %q, got: %q", expectedOutput, output.String())
+	}
+
+	shell.Eval("history -w")
+	data, err := os.ReadFile(histFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("Expected file %q, got: %q", content, string(data))
+	}
+}
+
+func TestHistorySessionsAppendOnExit(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+	os.WriteFile(histFile, []byte("echo old\n"), 0600)
+
+	first := New(Options{Env: []string{"HISTFILE=" + histFile}})
+	second := New(Options{Env: []string{"HISTFILE=" + histFile}})
+	first.readHistory(histFile, false)
+	second.readHistory(histFile, false)
+
+	first.addHistory("echo first")
+	second.addHistory("echo second")
+	if err := first.appendHistory(histFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.appendHistory(histFile); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := readHistoryFile(histFile)
+	file := Shell{history: entries}
+	expected := []string{"echo old", "echo first", "echo second"}
+	if !slices.Equal(file.historyLines(), expected) {
+		t.Errorf("Expected file %q, got: %q", expected, file.historyLines())
+	}
+
+	info, err := os.Stat(histFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got: %v", info.Mode().Perm())
+	}
+}
+
+func TestHistoryAppendThenReadNew(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+	shell := New(Options{Env: []string{"HISTFILE=" + histFile}})
+
+	shell.addHistory("echo a")
+	shell.Eval("history -a")
+	shell.Eval("history -n")
+
+	expected := []string{"echo a"}
+	if !slices.Equal(shell.historyLines(), expected) {
+		t.Errorf("Expected history %q, got: %q", expected, shell.historyLines())
+	}
+}
+
+func TestHistoryShare(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+	first := New(Options{Env: []string{"HISTFILE=" + histFile}})
+	second := New(Options{Env: []string{"HISTFILE=" + histFile}})
+
+	first.addHistory("echo a")
+	first.shareHistory(histFile)
+	second.addHistory("echo b")
+	second.shareHistory(histFile)
+	first.shareHistory(histFile)
+
+	expected := []string{"echo a", "echo b"}
+	for _, shell := range []*Shell{first, second} {
+		if !slices.Equal(shell.historyLines(), expected) {
+			t.Errorf("Expected history %q, got: %q", expected, shell.historyLines())
+		}
+	}
+
+	entries, _ := readHistoryFile(histFile)
+	if len(entries) != len(expected) {
+		t.Errorf("Expected %d entries in the file, got: %d", len(expected), len(entries))
+	}
+}
+
+func TestHistoryShareReadsAppendedLines(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+	shell := New(Options{Env: []string{"HISTFILE=" + histFile}})
+
+	shell.addHistory("echo a")
+	shell.addHistory("echo b")
+	shell.shareHistory(histFile)
+
+	// a line changed before the position is not read again, the appended
+	// one is
+	data, _ := os.ReadFile(histFile)
+	os.WriteFile(histFile, []byte(strings.Replace(string(data), "echo a", "echo z", 1)), 0600)
+	appendToFile(histFile, []string{"echo c"})
+	shell.shareHistory(histFile)
+
+	expected := []string{"echo a", "echo b", "echo c"}
+	if !slices.Equal(shell.historyLines(), expected) {
+		t.Errorf("Expected history %q, got: %q", expected, shell.historyLines())
+	}
+}
+
+func TestHistoryConcurrentAppend(t *testing.T) {
+	histFile := filepath.Join(t.TempDir(), "history")
+
+	var wg sync.WaitGroup
+	for session := 0; session < 8; session++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shell := New(Options{Env: []string{"HISTFILE=" + histFile, "HISTFILESIZE=-1"}})
+			for i := 0; i < 20; i++ {
+				shell.addHistory(fmt.Sprintf("echo %d %d", session, i))
+				if err := shell.appendHistory(histFile); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	entries, err := readHistoryFile(histFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 8*20 {
+		t.Errorf("Expected %d entries, got: %d", 8*20, len(entries))
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package shell
+
+import "os"
+
+// lockFile does nothing on systems without flock, sessions sharing a history
+// file are not protected from each other there.
+func lockFile(file *os.File, exclusive bool) error { return nil }
+
+func unlockFile(file *os.File) error { return nil }
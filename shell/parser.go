@@ -1,13 +1,17 @@
-package main
+package shell
 
 import (
 	"fmt"
 	"slices"
+	"strings"
 )
 
 // Lets use recusrive descent parser, ll(1)
 
 // Grammar
+// list -> spaces (function | pipe) spaces (list_op list)? | ε
+// function -> String spaces "(" ")" spaces "{" list "}"
+// list_op -> ";" | "&&" | "||"
 // pipe -> command | ε
 // command -> String spaces argument_list redirection_list command
 // argument_list -> String spaces argument_list | ε
@@ -26,6 +30,7 @@ type TokenType string
 type Token struct {
 	tokenType TokenType
 	literal   string
+	pos       int
 }
 
 const (
@@ -35,6 +40,7 @@ const (
 	EPSILON  = "EPSILON"
 	REDIRECT = "REDIRECT"
 	PIPE     = "PIPE"
+	LIST     = "LIST"
 )
 
 func NewToken(tokenType TokenType, literal string) Token {
@@ -62,7 +68,10 @@ func (l Lexar) peekNext() byte {
 }
 
 func (p *Lexar) next() byte {
-	p.i += 1
+	// an unterminated quote reads up to the end, never past it
+	if !p.eof() {
+		p.i += 1
+	}
 	if p.eof() {
 		return 0
 	}
@@ -86,6 +95,7 @@ func newLexar(input string) Lexar {
 
 func (p *Lexar) nextToken() Token {
 	var token Token
+	start := p.i
 	switch p.peek() {
 	case ' ':
 		p.readAllSpace()
@@ -102,8 +112,24 @@ func (p *Lexar) nextToken() Token {
 		p.next()
 		token = NewToken(STRING, result)
 	case '|':
-		p.next()
+		if p.next() == '|' {
+			p.next()
+			token = NewToken(LIST, "||")
+			break
+		}
 		token = NewToken(PIPE, "")
+	case ';':
+		p.next()
+		token = NewToken(LIST, ";")
+	case '&':
+		if p.peekNext() != '&' {
+			result := p.readLiteral()
+			token = NewToken(STRING, result)
+			break
+		}
+		p.next()
+		p.next()
+		token = NewToken(LIST, "&&")
 
 	case '1', '2':
 		start := p.i
@@ -123,12 +149,16 @@ func (p *Lexar) nextToken() Token {
 			p.next()
 		}
 		token = NewToken(REDIRECT, string(p.input[start:p.i]))
+	case '<':
+		p.next()
+		token = NewToken(REDIRECT, "<")
 	case 0:
 		token = NewToken(EOF, "")
 	default:
 		result := p.readLiteral()
 		token = NewToken(STRING, result)
 	}
+	token.pos = start
 
 	return token
 }
@@ -152,8 +182,12 @@ func (p *Lexar) readLiteral() string {
 	return p.input[start:p.i]
 }
 
+// metacharacters end a literal, everything else (including = and : so that
+// PATH=/usr/bin:/bin stays one word) is part of it.
+var metacharacters = []byte{0, ' ', '\t', '\n', '\'', '"', '\\', '|', '>', '<', '&', ';', '(', ')'}
+
 func isLiteral(b byte) bool {
-	return (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '/' || b == '_' || b == '-' || b == '.'
+	return !slices.Contains(metacharacters, b)
 
 }
 
@@ -232,6 +266,20 @@ type ParsedCommand struct {
 	Redirection []string
 }
 
+type ParsedPipeline struct {
+	// Operator is ";", "&&" or "||", it joins the pipeline to the previous one.
+	Operator string
+	Commands []ParsedCommand
+	Function *ParsedFunction
+}
+
+// ParsedFunction is a function definition, Source is the text of its body.
+type ParsedFunction struct {
+	Name   string
+	Body   []ParsedPipeline
+	Source string
+}
+
 func (p *Parser) parseSpaces() {
 	if p.currentToken.tokenType == SPACE {
 		p.nextToken() // consume SPACE
@@ -251,6 +299,112 @@ func (p *Parser) parsePipe() ([]ParsedCommand, error) {
 	return commands, nil
 }
 
+// ParseList parses a whole command line.
+func (p *Parser) ParseList() ([]ParsedPipeline, error) {
+	list, err := p.parseList("")
+	if err != nil {
+		return nil, err
+	}
+	if p.currentToken.tokenType != EOF {
+		return nil, fmt.Errorf("unexpected token: %s %s", p.currentToken.tokenType, p.currentToken.literal)
+	}
+
+	return list, nil
+}
+
+// list -> spaces (function | pipe) spaces (list_op list)? | ε
+// parseList stops before a word equal to terminator, "}" for function bodies.
+func (p *Parser) parseList(terminator string) ([]ParsedPipeline, error) {
+	var list []ParsedPipeline
+	operator := ""
+
+	for {
+		p.parseSpaces()
+		if p.currentToken.tokenType == EOF {
+			break
+		}
+		if terminator != "" && p.currentToken.tokenType == STRING && p.currentToken.literal == terminator {
+			break
+		}
+
+		pipeline := ParsedPipeline{Operator: operator}
+		if p.isFunctionDefinition() {
+			function, err := p.parseFunction()
+			if err != nil {
+				return nil, err
+			}
+			pipeline.Function = function
+		} else {
+			commands, err := p.ParseCommand()
+			if err != nil {
+				return nil, err
+			}
+			pipeline.Commands = commands
+		}
+		list = append(list, pipeline)
+
+		p.parseSpaces()
+		if p.currentToken.tokenType != LIST {
+			break
+		}
+		operator = p.currentToken.literal
+		p.nextToken()
+	}
+
+	return list, nil
+}
+
+func (p *Parser) isFunctionDefinition() bool {
+	if p.currentToken.tokenType != STRING {
+		return false
+	}
+
+	// the lexar is a value, scanning a copy looks ahead without consuming input
+	lexar := p.lexar
+	token := p.peekToken
+	if token.tokenType == SPACE {
+		token = lexar.nextToken()
+	}
+	if token.tokenType != STRING || token.literal != "(" {
+		return false
+	}
+	token = lexar.nextToken()
+
+	return token.tokenType == STRING && token.literal == ")"
+}
+
+// function -> String spaces "(" ")" spaces "{" list "}"
+func (p *Parser) parseFunction() (*ParsedFunction, error) {
+	function := &ParsedFunction{Name: p.currentToken.literal}
+
+	p.nextToken()
+	p.parseSpaces()
+	p.nextToken()
+	p.nextToken()
+	p.parseSpaces()
+
+	if p.currentToken.tokenType != STRING || p.currentToken.literal != "{" {
+		return nil, fmt.Errorf("expect { after %s(), got: %s", function.Name, p.currentToken.tokenType)
+	}
+	p.nextToken()
+	p.parseSpaces()
+	start := p.currentToken.pos
+
+	body, err := p.parseList("}")
+	if err != nil {
+		return nil, err
+	}
+	if p.currentToken.tokenType != STRING || p.currentToken.literal != "}" {
+		return nil, fmt.Errorf("expect } to close %s", function.Name)
+	}
+
+	function.Body = body
+	function.Source = strings.TrimSpace(p.lexar.input[start:p.currentToken.pos])
+	p.nextToken()
+
+	return function, nil
+}
+
 // command -> String spaces argument_list redirection_list
 func (p *Parser) ParseCommand() ([]ParsedCommand, error) {
 	var list []ParsedCommand
@@ -263,25 +417,33 @@ func (p *Parser) ParseCommand() ([]ParsedCommand, error) {
 		return nil, fmt.Errorf("expect command, got: %s", p.currentToken.tokenType)
 	}
 
+	word := p.currentToken.literal
+	p.nextToken()
+	// the pieces of a quoted command word, like 'ec'ho or FOO='a b', are one word
+	for p.currentToken.tokenType == STRING {
+		word += p.currentToken.literal
+		p.nextToken()
+	}
+
 	command := ParsedCommand{
-		Command:     Command(p.currentToken.literal),
+		Command:     Command(word),
 		Arguments:   []string{},
 		Redirection: []string{},
 	}
 
-	p.nextToken()
 	p.parseSpaces()
 
 	command.Arguments = p.ParseArgumentList()
 	command.Redirection = p.parseRedirectionList()
 	list = append(list, command)
 
-	if p.currentToken.tokenType == EOF {
+	p.parseSpaces()
+	if p.currentToken.tokenType != PIPE {
 		return list, nil
 	}
 
 	p.nextToken()
-	p.nextToken()
+	p.parseSpaces()
 
 	moreRedirection, err := p.ParseCommand()
 	if err != nil {
@@ -312,6 +474,7 @@ func (p *Parser) parseRedirectionList() []string {
 	}
 	list = append(list, p.currentToken.literal)
 	p.nextToken()
+	p.parseSpaces()
 
 	moreRedirection := p.parseRedirectionList()
 	list = append(list, moreRedirection...)
@@ -325,7 +488,7 @@ func (p *Parser) ParseArgumentList() []string {
 
 	var args []string
 
-	if p.currentToken.tokenType == EOF || p.currentToken.tokenType == REDIRECT || p.currentToken.tokenType == PIPE {
+	if p.currentToken.tokenType == EOF || p.currentToken.tokenType == REDIRECT || p.currentToken.tokenType == PIPE || p.currentToken.tokenType == LIST {
 		return args
 	}
 
@@ -0,0 +1,220 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+type TestCase struct {
+	input          string
+	outputCommand  string
+	outputArgs     []string
+	outputRedirect []string
+	pipe           *TestCaseData
+}
+
+type TestCaseData struct {
+	input          string
+	outputCommand  string
+	outputArgs     []string
+	outputRedirect []string
+}
+
+func TestParser(t *testing.T) {
+
+	testCases := []TestCase{
+		{
+			input:         "echo 'aa bb'",
+			outputCommand: "echo",
+			outputArgs:    []string{"aa bb"},
+		},
+		{
+			input:         "echo hello   world",
+			outputCommand: "echo",
+			outputArgs:    []string{"hello", " ", "world"},
+		},
+		{
+			input:         "echo 'example     world' 'hello''script' test''shell",
+			outputCommand: "echo",
+			outputArgs:    []string{"example     world", " ", "hello", "script", " ", "test", "", "shell"},
+		},
+
+		{
+			input:          "ls /tmp/baz > /tmp/foo/baz.md",
+			outputCommand:  "ls",
+			outputArgs:     []string{"/tmp/baz", " "},
+			outputRedirect: []string{">", "/tmp/foo/baz.md"},
+		},
+		{
+			input:          "echo 'Hello Maria' 1> /tmp/baz/foo.mdd",
+			outputCommand:  "echo",
+			outputArgs:     []string{"Hello Maria", " "},
+			outputRedirect: []string{"1>", "/tmp/baz/foo.mdd"},
+		},
+		{
+			input:          "echo 'Hello Maria' >> /tmp/baz/foo.mdd",
+			outputCommand:  "echo",
+			outputArgs:     []string{"Hello Maria", " "},
+			outputRedirect: []string{">>", "/tmp/baz/foo.mdd"},
+		},
+		{
+			input:          "echo 'Hello Maria' 1>> /tmp/baz/foo.mdd",
+			outputCommand:  "echo",
+			outputArgs:     []string{"Hello Maria", " "},
+			outputRedirect: []string{"1>>", "/tmp/baz/foo.mdd"},
+		},
+		{
+			input:         "ls --color=auto",
+			outputCommand: "ls",
+			outputArgs:    []string{"--color=auto"},
+		},
+		{
+			input:         "PATH=/usr/bin:/bin",
+			outputCommand: "PATH=/usr/bin:/bin",
+			outputArgs:    nil,
+		},
+		{
+			input:         "FOO='a b' 'ec'ho",
+			outputCommand: "FOO=a b",
+			outputArgs:    []string{"ec", "ho"},
+		},
+		{
+			input:         "cat /tmp/bar/file-37 | wc",
+			outputCommand: "cat",
+			outputArgs:    []string{"/tmp/bar/file-37", " "},
+			pipe: &TestCaseData{
+				outputCommand: "wc",
+				outputArgs:    nil,
+			},
+		},
+		{
+			input:          "cat < in.txt | wc -l",
+			outputCommand:  "cat",
+			outputArgs:     nil,
+			outputRedirect: []string{"<", "in.txt"},
+			pipe: &TestCaseData{
+				outputCommand: "wc",
+				outputArgs:    []string{"-l"},
+			},
+		},
+		{
+			input:         "tail -f /tmp/quz/file-19 | head -n 5",
+			outputCommand: "tail",
+			outputArgs:    []string{"-f", " ", "/tmp/quz/file-19", " "},
+			pipe: &TestCaseData{
+				outputCommand: "head",
+				outputArgs:    []string{"-n", " ", "5"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run("Running input"+testCase.input, func(t *testing.T) {
+			parser := NewParser(testCase.input)
+			cmds, err := parser.parsePipe()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			ret := cmds[0]
+
+			if testCase.outputCommand != string(ret.Command) {
+				t.Errorf("Expected command to be: %v, got: %v", testCase.outputCommand, ret.Command)
+			}
+
+			if !reflect.DeepEqual(testCase.outputArgs, ret.Arguments) {
+				t.Errorf("Expected to got: %#v, insted we have:%#v", testCase.outputArgs, ret.Arguments)
+			}
+			if testCase.outputRedirect != nil && !reflect.DeepEqual(testCase.outputRedirect, ret.Redirection) {
+				t.Errorf("Expected to got: %#v, insted we have:%#v", testCase.outputRedirect, ret.Redirection)
+
+			}
+
+			if testCase.pipe != nil {
+				if len(cmds) < 2 {
+					t.Fatalf("Expected pipe command, got: %#v", cmds)
+				}
+				if testCase.pipe.outputCommand != string(cmds[1].Command) {
+					t.Errorf("Expected pipe command to be %v, got: %v", testCase.pipe.outputCommand, string(cmds[1].Command))
+				}
+
+				if !reflect.DeepEqual(testCase.pipe.outputArgs, cmds[1].Arguments) {
+					t.Errorf("Expected to got: %#v, insted we have:%#v", testCase.pipe.outputArgs, cmds[1].Arguments)
+				}
+			}
+		})
+	}
+
+}
+
+func TestParseList(t *testing.T) {
+	parser := NewParser("a x | b && c;d || e ; f() { g; h | i; } ")
+	list, err := parser.ParseList()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operators := []string{}
+	commands := []string{}
+	for _, pipeline := range list {
+		operators = append(operators, pipeline.Operator)
+		if pipeline.Function != nil {
+			commands = append(commands, pipeline.Function.Name+"()")
+			continue
+		}
+		for _, command := range pipeline.Commands {
+			commands = append(commands, string(command.Command))
+		}
+	}
+
+	if !reflect.DeepEqual(operators, []string{"", "&&", ";", "||", ";"}) {
+		t.Errorf("Unexpected operators: %#v", operators)
+	}
+	if !reflect.DeepEqual(commands, []string{"a", "b", "c", "d", "e", "f()"}) {
+		t.Errorf("Unexpected commands: %#v", commands)
+	}
+
+	function := list[len(list)-1].Function
+	if function.Source != "g; h | i;" || len(function.Body) != 2 || len(function.Body[1].Commands) != 2 {
+		t.Errorf("Unexpected function body: %#v", function)
+	}
+}
+
+func TestLexerLiterals(t *testing.T) {
+	lexar := newLexar("PATH=/usr/bin:/bin a+b@c%d,e a;b")
+	literals := []string{}
+	for token := lexar.nextToken(); token.tokenType != EOF; token = lexar.nextToken() {
+		literals = append(literals, token.literal)
+	}
+
+	expected := []string{"PATH=/usr/bin:/bin", " ", "a+b@c%d,e", " ", "a", ";", "b"}
+	if !reflect.DeepEqual(literals, expected) {
+		t.Errorf("Expected tokens %#v, got: %#v", expected, literals)
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	if name, value, ok := assignment("_A1=x=y"); !ok || name != "_A1" || value != "x=y" {
+		t.Errorf("Expected _A1=x=y to assign x=y to _A1, got: %q %q %v", name, value, ok)
+	}
+	for _, word := range []string{"=x", "1A=x", "A-B=x", "echo"} {
+		if _, _, ok := assignment(word); ok {
+			t.Errorf("Expected %q not to be an assignment", word)
+		}
+	}
+}
+
+func TestLexerUnterminatedQuote(t *testing.T) {
+	for _, input := range []string{"echo 'abc", `echo "abc`} {
+		lexar := newLexar(input)
+		literals := []string{}
+		for token := lexar.nextToken(); token.tokenType != EOF; token = lexar.nextToken() {
+			literals = append(literals, token.literal)
+		}
+
+		expected := []string{"echo", " ", "abc"}
+		if !reflect.DeepEqual(literals, expected) {
+			t.Errorf("%s: expected the quote to run to the end, got: %#v", input, literals)
+		}
+	}
+}
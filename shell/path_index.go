@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// executableIndex is the sorted list of the executables in PATH that command
+// completion searches. Listing every PATH directory on each Tab is slow, so
+// the list is only built again when PATH or the modification time of one of
+// its directories changes, which adding or removing a file in it does.
+type executableIndex struct {
+	mu     sync.Mutex
+	built  bool
+	path   string
+	mtimes []time.Time
+	names  []string
+}
+
+// searchPath is $PATH with its directories made absolute against the working
+// directory of the shell, the way findFile resolves them, so that completion
+// offers what would run after a cd. An empty entry is the working directory.
+func (shell *Shell) searchPath() string {
+	directories := strings.Split(shell.getenv("PATH"), ":")
+	for i, directory := range directories {
+		directories[i] = shell.resolvePath(directory)
+	}
+	return strings.Join(directories, ":")
+}
+
+func directoryTimes(path string) []time.Time {
+	directories := strings.Split(path, ":")
+	mtimes := make([]time.Time, len(directories))
+	for i, directory := range directories {
+		if info, err := os.Stat(directory); err == nil {
+			mtimes[i] = info.ModTime()
+		}
+	}
+	return mtimes
+}
+
+func (index *executableIndex) current(path string) []string {
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	mtimes := directoryTimes(path)
+	if index.built && index.path == path && slices.EqualFunc(index.mtimes, mtimes, time.Time.Equal) {
+		return index.names
+	}
+
+	names := displayFilesFromDir(path)
+	sort.Strings(names)
+	index.names = slices.Compact(names)
+	index.path, index.mtimes, index.built = path, mtimes, true
+	return index.names
+}
+
+// withPrefix returns the executables in path that start with prefix, found
+// with a binary search in the sorted list.
+func (index *executableIndex) withPrefix(path string, prefix string) []string {
+	names := index.current(path)
+
+	start := sort.SearchStrings(names, prefix)
+	end := start
+	for end < len(names) && strings.HasPrefix(names[end], prefix) {
+		end++
+	}
+	return slices.Clone(names[start:end])
+}
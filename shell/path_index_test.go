@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExecutableIndex(t *testing.T) {
+	dir := t.TempDir()
+	other := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tool"), nil, 0755)
+	os.WriteFile(filepath.Join(dir, "readme"), nil, 0644)
+	os.Symlink(filepath.Join(dir, "tool"), filepath.Join(dir, "link"))
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(other, "tool"), nil, 0755)
+	os.WriteFile(filepath.Join(other, "task"), nil, 0755)
+
+	var index executableIndex
+
+	if got := index.withPrefix(dir, ""); !slices.Equal(got, []string{"link", "tool"}) {
+		t.Errorf("Expected only executables, got: %q", got)
+	}
+
+	// changing a file keeps the directory mtime, so the cached list is used
+	os.Chmod(filepath.Join(dir, "tool"), 0644)
+	if got := index.withPrefix(dir, "t"); !slices.Equal(got, []string{"tool"}) {
+		t.Errorf("Expected the cached list, got: %q", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "tool2"), nil, 0755)
+	if got := index.withPrefix(dir, "t"); !slices.Equal(got, []string{"tool2"}) {
+		t.Errorf("Expected the list to be built again, got: %q", got)
+	}
+
+	if got := index.withPrefix(dir+":"+other, "t"); !slices.Equal(got, []string{"task", "tool", "tool2"}) {
+		t.Errorf("Expected the list of the new PATH without duplicates, got: %q", got)
+	}
+
+	if got := index.withPrefix(dir, "zz"); len(got) != 0 {
+		t.Errorf("Expected no match, got: %q", got)
+	}
+}
+
+func TestAutocompleteExecutables(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "zztool"), nil, 0755)
+	os.WriteFile(filepath.Join(dir, "zzdata"), nil, 0644)
+
+	shell := New(Options{Env: []string{"PATH=" + dir}})
+	autocomplete := &AutoComplete{shell: shell}
+
+	autocompletions, _ := autocomplete.Do([]rune("zz"), 2)
+	if len(autocompletions) != 1 || string(autocompletions[0]) != "tool " {
+		t.Errorf("Expected only the executable to be completed, got: %q", autocompletions)
+	}
+}
+
+func TestAutocompleteRelativePath(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "bin"), 0755)
+	os.WriteFile(filepath.Join(dir, "bin", "zztool"), nil, 0755)
+
+	shell := New(Options{Env: []string{"PATH=bin"}})
+	autocomplete := &AutoComplete{shell: shell}
+	if autocompletions, _ := autocomplete.Do([]rune("zz"), 2); len(autocompletions) != 0 {
+		t.Errorf("Expected nothing outside the directory, got: %q", autocompletions)
+	}
+
+	shell.Eval("cd " + dir)
+	autocompletions, _ := autocomplete.Do([]rune("zz"), 2)
+	if len(autocompletions) != 1 || string(autocompletions[0]) != "tool " {
+		t.Errorf("Expected bin to be found after cd, got: %q", autocompletions)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+)
+
+// processGroup keeps the default of exec.CommandContext, which kills only the
+// command itself, on systems without process groups.
+func processGroup(cmd *exec.Cmd, pgid int, terminal *os.File) {}
+
+func takeTerminal(terminal *os.File) {}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// processGroup puts cmd in the process group pgid, a new one led by cmd when
+// pgid is 0, and makes cancellation kill the whole group, so children of a
+// runaway command are stopped too. A new group is made the foreground group
+// of terminal when there is one, the shell takes it back with takeTerminal.
+func processGroup(cmd *exec.Cmd, pgid int, terminal *os.File) {
+	attr := &syscall.SysProcAttr{Setpgid: true, Pgid: pgid}
+	if terminal != nil && pgid == 0 {
+		attr.Foreground = true
+		attr.Ctty = int(terminal.Fd())
+	}
+	cmd.SysProcAttr = attr
+	cmd.Cancel = func() error {
+		group := pgid
+		if group == 0 {
+			group = cmd.Process.Pid
+		}
+		return syscall.Kill(-group, syscall.SIGKILL)
+	}
+}
+
+// takeTerminal makes the group of the shell the foreground group of terminal
+// again. The shell is in the background when it asks, SIGTTOU would stop it.
+func takeTerminal(terminal *os.File) {
+	if terminal == nil {
+		return
+	}
+
+	signal.Ignore(syscall.SIGTTOU)
+	defer signal.Reset(syscall.SIGTTOU)
+	unix.IoctlSetPointerInt(int(terminal.Fd()), unix.TIOCSPGRP, syscall.Getpgrp())
+}
@@ -0,0 +1,256 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPrompts are the prompts used while PS1, PS2 or PS4 is not set.
+var defaultPrompts = map[string]string{
+	"PS1": "$ ",
+	"PS2": "> ",
+	"PS4": "+ ",
+}
+
+// prompt expands the prompt variable name: the backslash escapes first, then
+// $NAME, ${NAME}, $(command) and `command` in the result, as bash does with
+// promptvars on. It is expanded each time it is drawn, so commands in it
+// run again for every prompt. What the escapes put in, like a directory
+// name, is quoted so that it is not expanded in turn.
+func (shell *Shell) prompt(name string) string {
+	value, ok := shell.lookupEnv(name)
+	if !ok {
+		value = defaultPrompts[name]
+	}
+	return shell.substitutePrompt(shell.decodePrompt(value, time.Now()))
+}
+
+// decodePrompt replaces the backslash escapes of a prompt. \[ and \] only
+// mark where the non-printing characters are, the line editor leaves color
+// sequences out when measuring the prompt so they are dropped.
+func (shell *Shell) decodePrompt(value string, now time.Time) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch escape := value[i]; escape {
+		case 'a':
+			builder.WriteByte('\a')
+		case 'e':
+			builder.WriteByte('\033')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case '\\':
+			builder.WriteByte('\\')
+		case '[', ']':
+		case 'u':
+			builder.WriteString(promptQuote(shell.userName()))
+		case 'h', 'H':
+			host, _ := os.Hostname()
+			if escape == 'h' {
+				host, _, _ = strings.Cut(host, ".")
+			}
+			builder.WriteString(promptQuote(host))
+		case 'g':
+			builder.WriteString(promptQuote(shell.gitPrompt()))
+		case 'w':
+			builder.WriteString(promptQuote(shell.promptDirectory(false)))
+		case 'W':
+			builder.WriteString(promptQuote(shell.promptDirectory(true)))
+		case '$':
+			if os.Geteuid() == 0 {
+				builder.WriteByte('#')
+			} else {
+				builder.WriteString(`\$`)
+			}
+		case '?':
+			builder.WriteString(strconv.Itoa(shell.lastStatus))
+		case '!':
+			builder.WriteString(strconv.Itoa(len(shell.history) + 1))
+		case 's':
+			builder.WriteString(promptQuote(filepath.Base(os.Args[0])))
+		case 't':
+			builder.WriteString(now.Format("15:04:05"))
+		case 'T':
+			builder.WriteString(now.Format("03:04:05"))
+		case '@':
+			builder.WriteString(now.Format("03:04 PM"))
+		case 'A':
+			builder.WriteString(now.Format("15:04"))
+		case 'd':
+			builder.WriteString(now.Format("Mon Jan 02"))
+		case 'D':
+			format, rest, ok := strings.Cut(value[i+1:], "}")
+			if !strings.HasPrefix(format, "{") || !ok {
+				builder.WriteString(`\D`)
+				continue
+			}
+			if format == "{" {
+				format += "%X"
+			}
+			builder.WriteString(promptQuote(strftime(format[1:], now)))
+			i = len(value) - len(rest) - 1
+		case '0', '1', '2', '3':
+			// \nnn is the character with octal code nnn
+			end := i
+			for end < len(value) && end < i+3 && value[end] >= '0' && value[end] <= '7' {
+				end++
+			}
+			code, _ := strconv.ParseUint(value[i:end], 8, 8)
+			builder.WriteByte(byte(code))
+			i = end - 1
+		default:
+			builder.WriteByte('\\')
+			builder.WriteByte(escape)
+		}
+	}
+	return builder.String()
+}
+
+// promptQuoted are the characters substitutePrompt takes literally after a
+// backslash, as inside double quotes.
+const promptQuoted = "$`\"\\"
+
+// promptQuote puts a backslash before the characters substitutePrompt would
+// expand, as bash does with what the escapes of a prompt put in.
+func promptQuote(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if strings.IndexByte(promptQuoted, value[i]) >= 0 {
+			builder.WriteByte('\\')
+		}
+		builder.WriteByte(value[i])
+	}
+	return builder.String()
+}
+
+func (shell *Shell) userName() string {
+	if account, err := user.Current(); err == nil {
+		return account.Username
+	}
+	return shell.getenv("USER")
+}
+
+// promptDirectory is the working directory with the home directory written
+// as ~, or only its last element when base is set.
+func (shell *Shell) promptDirectory(base bool) string {
+	directory := shell.workingDirectory()
+	home, _ := shell.homeDirectory()
+	home = strings.TrimSuffix(home, "/")
+
+	switch {
+	case home != "" && directory == home:
+		return "~"
+	case base:
+		return filepath.Base(directory)
+	case home != "" && strings.HasPrefix(directory, home+"/"):
+		return "~" + directory[len(home):]
+	}
+	return directory
+}
+
+// substitutePrompt expands the parameters and command substitutions of a
+// decoded prompt. A $ that starts neither is kept, and a backslash before
+// $, `, " or another backslash is removed and the character kept as it is.
+func (shell *Shell) substitutePrompt(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && strings.IndexByte(promptQuoted, value[i+1]) >= 0:
+			builder.WriteByte(value[i+1])
+			i++
+		case value[i] == '`':
+			end := strings.IndexByte(value[i+1:], '`')
+			if end < 0 {
+				builder.WriteString(value[i:])
+				return builder.String()
+			}
+			builder.WriteString(shell.commandOutput(value[i+1 : i+1+end]))
+			i += end + 1
+		case value[i] == '$' && strings.HasPrefix(value[i+1:], "("):
+			end := closingParenthesis(value, i+1)
+			if end < 0 {
+				builder.WriteString(value[i:])
+				return builder.String()
+			}
+			builder.WriteString(shell.commandOutput(value[i+2 : end]))
+			i = end
+		case value[i] == '$' && strings.HasPrefix(value[i+1:], "{"):
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				builder.WriteString(value[i:])
+				return builder.String()
+			}
+			builder.WriteString(shell.parameter(value[i+2 : i+end]))
+			i += end
+		case value[i] == '$' && i+1 < len(value) && value[i+1] == '?':
+			builder.WriteString(strconv.Itoa(shell.lastStatus))
+			i++
+		case value[i] == '$':
+			end := i + 1
+			for end < len(value) && (value[end] == '_' || isLetter(value[end]) || (end > i+1 && isDigit(value[end]))) {
+				end++
+			}
+			if end == i+1 {
+				builder.WriteByte('$')
+				continue
+			}
+			builder.WriteString(shell.parameter(value[i+1 : end]))
+			i = end - 1
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
+
+func (shell *Shell) parameter(name string) string {
+	if name == "?" {
+		return strconv.Itoa(shell.lastStatus)
+	}
+	return shell.getenv(name)
+}
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// closingParenthesis is the index of the parenthesis that closes the one at
+// open, or -1.
+func closingParenthesis(value string, open int) int {
+	depth := 0
+	for i := open; i < len(value); i++ {
+		switch value[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// commandOutput runs line and returns what it printed without the trailing
+// newlines, as a command substitution does.
+func (shell *Shell) commandOutput(line string) string {
+	var stdout bytes.Buffer
+	saved := shell.stdout
+	shell.stdout = &stdout
+	shell.EvalContext(shell.Context(), line)
+	shell.stdout = saved
+	return strings.TrimRight(stdout.String(), "\n")
+}
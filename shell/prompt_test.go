@@ -0,0 +1,61 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodePrompt(t *testing.T) {
+	home := t.TempDir()
+	os.Mkdir(filepath.Join(home, "src"), 0755)
+	now := time.Date(2024, 3, 5, 14, 7, 9, 0, time.Local)
+
+	shell := New(Options{Env: []string{"HOME=" + home}, Dir: filepath.Join(home, "src")})
+	if got := shell.decodePrompt(`\w \W \t \[\e[0m\]`, now); got != "~/src src 14:07:09 \033[0m" {
+		t.Errorf("Expected the escapes to be decoded, got: %q", got)
+	}
+}
+
+func TestPromptSubstitution(t *testing.T) {
+	shell := New(Options{Env: []string{"PS1=$NAME ${NAME}_1 $(echo a) `echo b` $ ", "NAME=box"}})
+	if got := shell.prompt("PS1"); got != "box box_1 a b $ " {
+		t.Errorf("Expected parameters and commands to be substituted, got: %q", got)
+	}
+}
+
+func TestPromptQuotesEscapes(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "$(touch pwned) `touch pwned`")
+	os.Mkdir(dir, 0755)
+
+	shell := New(Options{Env: []string{"PS1=\\w:", "HOME=/nonexistent"}, Dir: dir})
+	if got := shell.prompt("PS1"); got != dir+":" {
+		t.Errorf("Expected the directory as it is, got: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+		t.Errorf("Expected the directory name not to be run")
+	}
+}
+
+func TestContinuationLines(t *testing.T) {
+	cases := []Case{
+		{input: "echo 'a\nb'\n", output: "a\nb\n", name: "open quote"},
+		{input: "echo a\\\nb\n", output: "ab\n", name: "backslash"},
+		{input: "echo a\\\\\n", output: "a\\\n", name: "escaped backslash"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			shell := New(Options{Stdin: strings.NewReader(testCase.input), Stdout: &output, Stderr: &output, Env: []string{}})
+			shell.startCli()
+
+			if output.String() != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, output.String())
+			}
+		})
+	}
+}
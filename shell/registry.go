@@ -0,0 +1,275 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// CompletionHint tells the autocompletion what the arguments of a builtin are.
+type CompletionHint int
+
+const (
+	CompleteNone CompletionHint = iota
+	CompleteFiles
+	CompleteDirectories
+	CompleteCommands
+)
+
+// FlagSpec documents one option of a builtin, Arg names its value if any.
+type FlagSpec struct {
+	Name string
+	Arg  string
+	Help string
+}
+
+type CommandSpec struct {
+	Name         Command
+	NeedsRawArgs bool
+	Handler      func(shell *Shell, args []string) (string, error)
+	// Usage is the one line synopsis, for example "cd [dir]".
+	Usage      string
+	Help       string
+	Flags      []FlagSpec
+	Completion CompletionHint
+}
+
+// Registry holds the builtins of a shell. It is safe to change it while the
+// shell is running, a lookup always sees either the old or the new spec.
+type Registry struct {
+	mu    sync.RWMutex
+	specs map[Command]CommandSpec
+}
+
+func NewRegistry() *Registry {
+	registry := &Registry{specs: map[Command]CommandSpec{}}
+
+	// the defaults all have a handler, there is nothing for Register to check
+	for _, spec := range defaultBuiltins() {
+		registry.specs[spec.Name] = spec
+	}
+
+	return registry
+}
+
+func defaultBuiltins() []CommandSpec {
+	return []CommandSpec{
+		{
+			Name:         EchoCommand,
+			NeedsRawArgs: true,
+			Handler:      (*Shell).handleEchoCommand,
+			Usage:        "echo [arg ...]",
+			Help:         "Write the arguments to standard output.",
+			Completion:   CompleteFiles,
+		},
+		{
+			Name:       ExitCommand,
+			Handler:    (*Shell).handleExitCommand,
+			Usage:      "exit [n]",
+			Help:       "Exit the shell with status n, or 0 when n is omitted.",
+			Completion: CompleteNone,
+		},
+		{
+			Name:    TypeCommand,
+			Handler: (*Shell).handleTypeCommand,
+			Usage:   "type [-afptP] name [name ...]",
+			Help:    "Display how each name would be interpreted if used as a command: alias, keyword, function, builtin or file.",
+			Flags: []FlagSpec{
+				{Name: "-a", Help: "display every place that contains name, not only the first"},
+				{Name: "-t", Help: "print a single word: alias, keyword, function, builtin or file"},
+				{Name: "-p", Help: "print the path of the file that would be executed, if any"},
+				{Name: "-P", Help: "search PATH for name even if it is an alias, function or builtin"},
+			},
+			Completion: CompleteCommands,
+		},
+		{
+			Name:       PwdCommand,
+			Handler:    (*Shell).handlePwdCommand,
+			Usage:      "pwd",
+			Help:       "Print the current working directory.",
+			Completion: CompleteNone,
+		},
+		{
+			Name:       CdCommand,
+			Handler:    (*Shell).handleCdCommand,
+			Usage:      "cd dir",
+			Help:       "Change the current directory to dir, ~ is the home directory.",
+			Completion: CompleteDirectories,
+		},
+		{
+			Name:    HistoryCommand,
+			Handler: (*Shell).handleHistoryCommand,
+			Usage:   "history [-c] [-d offset] [n] | history -anrw [file] | history -ps arg ...",
+			Help:    "Display the history list with line numbers, only the last n entries when n is given. Without a file -a, -n, -r and -w use $HISTFILE.",
+			Flags: []FlagSpec{
+				{Name: "-c", Help: "clear the history list"},
+				{Name: "-d", Arg: "offset", Help: "delete the entry at offset, or the entries start-end, negative offsets count from the end"},
+				{Name: "-a", Arg: "file", Help: "append the entries added since the last -a to file"},
+				{Name: "-n", Arg: "file", Help: "append the lines of file not read yet to the history list"},
+				{Name: "-r", Arg: "file", Help: "read file and append its lines to the history list"},
+				{Name: "-w", Arg: "file", Help: "write the history list to file, replacing its contents"},
+				{Name: "-p", Arg: "arg ...", Help: "expand history references in each arg and print them, without running or storing them"},
+				{Name: "-s", Arg: "arg ...", Help: "store the args as one entry instead of the history command"},
+			},
+			Completion: CompleteFiles,
+		},
+		{
+			Name:         TimeoutCommand,
+			NeedsRawArgs: true,
+			Handler:      (*Shell).handleTimeoutCommand,
+			Usage:        "timeout duration command [arg ...]",
+			Help:         "Run command and kill it with its children if it is still running after duration. The status is 124 when it timed out.",
+			Completion:   CompleteCommands,
+		},
+		{
+			Name:         CommandCommand,
+			NeedsRawArgs: true,
+			Handler:      (*Shell).handleCommandCommand,
+			Usage:        "command [-v|-V] name [arg ...]",
+			Help:         "Run name with its arguments, skipping functions and aliases, or describe name.",
+			Flags: []FlagSpec{
+				{Name: "-v", Help: "print the path or the name that would be run"},
+				{Name: "-V", Help: "print a description of name like type does"},
+			},
+			Completion: CompleteCommands,
+		},
+		{
+			Name:       AliasCommand,
+			Handler:    (*Shell).handleAliasCommand,
+			Usage:      "alias [name[=value] ...]",
+			Help:       "Define or display aliases. An alias replaces the first word of a command.",
+			Completion: CompleteNone,
+		},
+		{
+			Name:       UnaliasCommand,
+			Handler:    (*Shell).handleUnaliasCommand,
+			Usage:      "unalias [-a] name [name ...]",
+			Help:       "Remove each name from the list of aliases, -a removes all of them.",
+			Completion: CompleteNone,
+		},
+		{
+			Name:    HashCommand,
+			Handler: (*Shell).handleHashCommand,
+			Usage:   "hash [-r] [-p path] [-d] [name ...]",
+			Help:    "Remember the full path of each name, or list the remembered commands when no name is given. Assigning PATH forgets them all.",
+			Flags: []FlagSpec{
+				{Name: "-r", Help: "forget all remembered locations"},
+				{Name: "-p", Arg: "path name", Help: "use path as the full path of name"},
+				{Name: "-d", Arg: "name", Help: "forget the remembered location of each name"},
+			},
+			Completion: CompleteCommands,
+		},
+		{
+			Name:    HistdbCommand,
+			Handler: (*Shell).handleHistdbCommand,
+			Usage:   "histdb [--cwd[=dir]] [--failed] [--since time] [--session] [-n count] [text]",
+			Help:    "Search the history database in $HISTDB, which records the directory, start time, duration and exit status of every command. It keeps the last $HISTFILESIZE commands.",
+			Flags: []FlagSpec{
+				{Name: "--cwd", Help: "only commands run in the current directory, or in dir with --cwd=dir"},
+				{Name: "--failed", Help: "only commands with a non-zero exit status"},
+				{Name: "--since", Arg: "time", Help: "only commands started after time, an age like 2h or 3d or a date"},
+				{Name: "--session", Help: "only commands of this shell"},
+				{Name: "-n", Arg: "count", Help: "print at most the last count matches"},
+			},
+		},
+		{
+			Name:    CompleteCommand,
+			Handler: (*Shell).handleCompleteCommand,
+			Usage:   "complete [-p] [-r] [-abcdf] [-o option] [-W wordlist] [-F function] [-C command] [name ...]",
+			Help:    "Say how the arguments of each name are completed, or print the completions that were set. -F functions and -C commands get the command, the word and the word before it, and print the candidates.",
+			Flags: []FlagSpec{
+				{Name: "-p", Help: "print the completions of each name, or all of them"},
+				{Name: "-r", Help: "remove the completions of each name, or all of them"},
+				{Name: "-a", Help: "complete aliases"},
+				{Name: "-b", Help: "complete builtins"},
+				{Name: "-c", Help: "complete commands"},
+				{Name: "-d", Help: "complete directories"},
+				{Name: "-f", Help: "complete files"},
+				{Name: "-o", Arg: "option", Help: "filenames, dirnames or default"},
+				{Name: "-W", Arg: "wordlist", Help: "complete the words of wordlist"},
+				{Name: "-F", Arg: "function", Help: "complete the lines function prints"},
+				{Name: "-C", Arg: "command", Help: "complete the lines command prints"},
+			},
+			Completion: CompleteCommands,
+		},
+		{
+			Name:    CompgenCommand,
+			Handler: (*Shell).handleCompgenCommand,
+			Usage:   "compgen [-abcdf] [-o option] [-W wordlist] [-F function] [-C command] [word]",
+			Help:    "Print the completions of word, the options are those of complete.",
+			Flags: []FlagSpec{
+				{Name: "-a", Help: "complete aliases"},
+				{Name: "-b", Help: "complete builtins"},
+				{Name: "-c", Help: "complete commands"},
+				{Name: "-d", Help: "complete directories"},
+				{Name: "-f", Help: "complete files"},
+				{Name: "-o", Arg: "option", Help: "filenames, dirnames or default"},
+				{Name: "-W", Arg: "wordlist", Help: "complete the words of wordlist"},
+				{Name: "-F", Arg: "function", Help: "complete the lines function prints"},
+				{Name: "-C", Arg: "command", Help: "complete the lines command prints"},
+			},
+		},
+		{
+			Name:    SetCommand,
+			Handler: (*Shell).handleSetCommand,
+			Usage:   "set [-x] [+x] [-o option] [+o option]",
+			Help:    "Turn shell options on with - and off with +, or print the variables when no option is given.",
+			Flags: []FlagSpec{
+				{Name: "-x", Help: "print each command and its arguments to standard error after $PS4 before it runs"},
+				{Name: "-o", Arg: "option", Help: "turn on option, only xtrace is known, or list the options when none is given"},
+			},
+		},
+		{
+			Name:       HelpCommand,
+			Handler:    (*Shell).handleHelpCommand,
+			Usage:      "help [builtin ...]",
+			Help:       "Display information about builtins, a summary of all of them when no name is given.",
+			Completion: CompleteCommands,
+		},
+	}
+}
+
+// Register adds spec, replacing a builtin of the same name. A builtin has to
+// have a Handler, spec is rejected with an error when it is nil.
+func (r *Registry) Register(spec CommandSpec) error {
+	if spec.Handler == nil {
+		return fmt.Errorf("shell: builtin %s has no handler", spec.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.specs[spec.Name] = spec
+	return nil
+}
+
+func (r *Registry) Unregister(name Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.specs, name)
+}
+
+func (r *Registry) Lookup(name Command) (CommandSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	spec, ok := r.specs[name]
+	return spec, ok
+}
+
+// Names returns the registered builtins in sorted order.
+func (r *Registry) Names() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]Command, 0, len(r.specs))
+	for name := range r.specs {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+
+	return names
+}
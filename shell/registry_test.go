@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegistryNames(t *testing.T) {
+	registry := NewRegistry()
+
+	names := registry.Names()
+	for _, name := range []Command{CdCommand, EchoCommand, ExitCommand, PwdCommand, TypeCommand} {
+		if !slices.Contains(names, name) {
+			t.Errorf("Expected %q to be a builtin, got: %v", name, names)
+		}
+	}
+	if !slices.IsSorted(names) {
+		t.Errorf("Expected builtins in sorted order, got: %v", names)
+	}
+
+	for _, name := range registry.Names() {
+		spec, _ := registry.Lookup(name)
+		if spec.Name != name {
+			t.Errorf("Expected spec registered as %q to be named %q, got: %q", name, name, spec.Name)
+		}
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{}})
+
+	err := shell.Builtins().Register(CommandSpec{
+		Name: "greet",
+		Handler: func(shell *Shell, args []string) (string, error) {
+			return "hello " + strings.Join(args, " "), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shell.Builtins().Register(CommandSpec{
+		Name: EchoCommand,
+		Handler: func(shell *Shell, args []string) (string, error) {
+			return "overridden", nil
+		},
+	})
+
+	shell.Eval("greet world")
+	shell.Eval("echo abc")
+	shell.Eval("type greet")
+
+	expected := "hello world\noverridden\ngreet is a shell builtin\n"
+	if output.String() != expected {
+		t.Errorf("Expected result to be %q, got: %q", expected, output.String())
+	}
+
+	shell.Builtins().Unregister("greet")
+	output.Reset()
+	shell.Eval("greet world")
+
+	if !strings.Contains(errout.String(), "greet: command not found") {
+		t.Errorf("Expected unregistered builtin to be gone, got: %q", errout.String())
+	}
+}
+
+func TestRegisterWithoutHandler(t *testing.T) {
+	registry := NewRegistry()
+
+	if err := registry.Register(CommandSpec{Name: "nohandler"}); err == nil {
+		t.Errorf("Expected a builtin without a handler to be rejected")
+	}
+	if _, ok := registry.Lookup("nohandler"); ok {
+		t.Errorf("Expected a rejected builtin not to be registered")
+	}
+}
+
+func TestAutocompleteUsesRegistry(t *testing.T) {
+	shell := New(Options{Env: []string{}})
+	shell.Builtins().Register(CommandSpec{
+		Name: "zzcustom",
+		Handler: func(shell *Shell, args []string) (string, error) {
+			return "", nil
+		},
+	})
+
+	autocomplete := &AutoComplete{shell: shell}
+	autocompletions, _ := autocomplete.Do([]rune("zzc"), 3)
+
+	if len(autocompletions) != 1 || string(autocompletions[0]) != "ustom " {
+		t.Errorf("Expected registered builtin to be completed, got: %q", autocompletions)
+	}
+}
@@ -0,0 +1,48 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"os"
+	"strings"
+)
+
+// subshell returns a copy of the shell that writes to stdout. It shares the
+// builtins but has its own environment and working directory.
+func (shell *Shell) subshell(stdout io.Writer) *Shell {
+	return &Shell{
+		in:        shell.in,
+		stdin:     shell.stdin,
+		stdout:    stdout,
+		stderr:    shell.stderr,
+		directory: shell.workingDirectory(),
+		env:       maps.Clone(shell.env),
+		registry:  shell.Builtins(),
+		aliases:   maps.Clone(shell.aliases),
+		functions: maps.Clone(shell.functions),
+	}
+}
+
+// runScriptFile runs an executable file without a "#!" line, which the kernel
+// refuses with ENOEXEC, in a subshell the way POSIX shells do.
+func (shell *Shell) runScriptFile(ctx context.Context, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout bytes.Buffer
+	status, err := shell.subshell(&stdout).Run(ctx, string(data))
+	output := strings.TrimRight(stdout.String(), "\n")
+
+	if err != nil {
+		return output, err
+	}
+	if status != 0 {
+		return output, &statusError{status: status}
+	}
+
+	return output, nil
+}
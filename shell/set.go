@@ -0,0 +1,129 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const setUsage = "set: usage: set [-x] [+x] [-o option] [+o option]"
+
+// shellOptions are the options set turns on and off, by their -o name.
+var shellOptions = map[string]byte{"xtrace": 'x'}
+
+func (shell *Shell) option(name string) *bool {
+	switch name {
+	case "xtrace":
+		return &shell.xtrace
+	}
+	return nil
+}
+
+// handleSetCommand turns the options on with - and off with +. Without
+// arguments it prints the variables, -o and +o alone print the options.
+func (shell *Shell) handleSetCommand(args []string) (string, error) {
+	usage := func(message string) error {
+		return &statusError{status: 2, message: "set: " + message + "\n" + setUsage}
+	}
+
+	if len(args) == 0 {
+		lines := []string{}
+		for _, item := range shell.environ() {
+			name, value, _ := strings.Cut(item, "=")
+			lines = append(lines, name+"="+traceWord(value))
+		}
+		sort.Strings(lines)
+		return strings.Join(lines, "\n"), nil
+	}
+
+	for len(args) > 0 {
+		flag := args[0]
+		args = args[1:]
+		if len(flag) < 2 || (flag[0] != '-' && flag[0] != '+') {
+			return "", usage(flag + ": invalid option")
+		}
+		on := flag[0] == '-'
+
+		if flag[1:] != "o" {
+			for _, letter := range flag[1:] {
+				name := ""
+				for option, short := range shellOptions {
+					if rune(short) == letter {
+						name = option
+					}
+				}
+				if name == "" {
+					return "", usage(fmt.Sprintf("%c%c: invalid option", flag[0], letter))
+				}
+				*shell.option(name) = on
+			}
+			continue
+		}
+
+		if len(args) == 0 {
+			return shell.printOptions(on), nil
+		}
+		value := shell.option(args[0])
+		if value == nil {
+			return "", usage(args[0] + ": invalid option name")
+		}
+		*value = on
+		args = args[1:]
+	}
+	return "", nil
+}
+
+// printOptions lists the options as set -o does, or as the set commands
+// that restore them for set +o.
+func (shell *Shell) printOptions(table bool) string {
+	names := make([]string, 0, len(shellOptions))
+	for name := range shellOptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, name := range names {
+		on := *shell.option(name)
+		switch {
+		case table && on:
+			lines = append(lines, fmt.Sprintf("%-15s\ton", name))
+		case table:
+			lines = append(lines, fmt.Sprintf("%-15s\toff", name))
+		case on:
+			lines = append(lines, "set -o "+name)
+		default:
+			lines = append(lines, "set +o "+name)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trace prints a command about to run with $PS4 before it, for set -x. The
+// raw arguments of a builtin are joined into words as the others are, and
+// commands run while $PS4 is expanded are not traced.
+func (shell *Shell) trace(stderr io.Writer, command Command, args []string) {
+	shell.xtrace = false
+	prefix := shell.prompt("PS4")
+	shell.xtrace = true
+
+	if spec, ok := shell.Builtins().Lookup(command); ok && spec.NeedsRawArgs {
+		args = filterParams(args)
+	}
+	words := []string{traceWord(string(command))}
+	for _, arg := range args {
+		words = append(words, traceWord(arg))
+	}
+	fmt.Fprintln(stderr, prefix+strings.Join(words, " "))
+}
+
+// traceWord quotes a word that would not read back as itself.
+func traceWord(word string) string {
+	if word == "" || strings.ContainsFunc(word, func(r rune) bool {
+		return r > 127 || !isLetter(byte(r)) && !isDigit(byte(r)) && !strings.ContainsRune("_@%+=:,./-", r)
+	}) {
+		return singleQuote(word)
+	}
+	return word
+}
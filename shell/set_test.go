@@ -0,0 +1,46 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSetCommand(t *testing.T) {
+	cases := []struct {
+		name   string
+		env    []string
+		input  []string
+		output string
+		err    string
+	}{
+		{name: "xtrace", input: []string{"set -x", "echo 'a b' c"}, output: "a b c", err: "+ echo 'a b' c"},
+		{name: "xtrace off", input: []string{"set -x", "set +x", "echo a"}, output: "a", err: "+ set +x"},
+		{name: "long name", input: []string{"set -o xtrace", "echo a"}, output: "a", err: "+ echo a"},
+		{name: "PS4", env: []string{"PS4=>> "}, input: []string{"set -x", "pwd > /dev/null"}, err: ">> pwd"},
+		{name: "list options", input: []string{"set -o"}, output: "xtrace         \toff"},
+		{name: "restore options", input: []string{"set -x", "set +o"}, output: "set -o xtrace", err: "+ set +o"},
+		{name: "variables", env: []string{"A=1", "B=two words"}, input: []string{"set"}, output: "A=1\nB='two words'"},
+		{name: "invalid option", input: []string{"set -e"}, err: "set: -e: invalid option\n" + setUsage},
+		{name: "invalid option name", input: []string{"set -o nope"}, err: "set: nope: invalid option name\n" + setUsage},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := New(Options{Stdout: &output, Stderr: &errout, Env: testCase.env})
+
+			for _, line := range testCase.input {
+				shell.Eval(line)
+			}
+
+			if got := strings.TrimRight(output.String(), "\n"); got != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, got)
+			}
+			if got := strings.TrimRight(errout.String(), "\n"); got != testCase.err {
+				t.Errorf("Expected error to be %q, got: %q", testCase.err, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,1242 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/chzyer/readline"
+)
+
+type Command string
+
+const (
+	EchoCommand     Command = "echo"
+	ExitCommand     Command = "exit"
+	TypeCommand     Command = "type"
+	PwdCommand      Command = "pwd"
+	CdCommand       Command = "cd"
+	HistoryCommand  Command = "history"
+	HelpCommand     Command = "help"
+	TimeoutCommand  Command = "timeout"
+	HashCommand     Command = "hash"
+	AliasCommand    Command = "alias"
+	UnaliasCommand  Command = "unalias"
+	CommandCommand  Command = "command"
+	HistdbCommand   Command = "histdb"
+	CompleteCommand Command = "complete"
+	CompgenCommand  Command = "compgen"
+	SetCommand      Command = "set"
+)
+
+type Shell struct {
+	in io.Reader
+	// stdin is what the commands being run read: the file of a <
+	// redirection, or the input of the pipeline stage a builtin or function
+	// runs in. Without either they get none.
+	stdin               io.Reader
+	stdout              io.Writer
+	stderr              io.Writer
+	directory           string
+	env                 map[string]string
+	registry            *Registry
+	hash                commandHash
+	aliases             map[string]string
+	functions           map[string]*ParsedFunction
+	callDepth           int
+	lastSubstitution    *substitution
+	historyReadEntries  int
+	currentLine         string
+	ctx                 context.Context
+	history             []historyEntry
+	historyWrittenIndex int
+	// historyPosition is how far $HISTSHARE has read the history file.
+	historyPosition historyPosition
+	session         string
+	completions     map[string]completionSpec
+	executables     executableIndex
+	// databaseLines are the commands of $HISTDB the line editor offers, read
+	// once when the interactive loop starts and added to as commands are
+	// recorded.
+	databaseLines []string
+	// userNames and hostNames are what ~user and host@ complete with, from
+	// /etc/passwd and /etc/hosts.
+	userNames cachedNames
+	hostNames cachedNames
+	// databaseCount is the number of records in $HISTDB as of its last
+	// write, so that it is only compacted once it has too many.
+	databaseCount historyCount
+	// lastStatus is the status of the last command line, for \? and $? in
+	// the prompt.
+	lastStatus int
+	xtrace     bool
+	git        gitPromptCache
+}
+
+// Options configures a Shell created with New. Zero values fall back to the
+// process: os.Stdin, os.Stdout, os.Stderr, os.Environ() and os.Getwd().
+type Options struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	// Env holds "KEY=value" pairs, nil inherits the process environment.
+	Env []string
+	Dir string
+}
+
+// ExitError is returned by Eval and Run when the exit builtin is executed.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit %d", e.Code)
+}
+
+// statusError is a command failure that carries its exit status.
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+func exitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.status
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return 1
+}
+
+func New(opts Options) *Shell {
+	shell := &Shell{
+		in:        opts.Stdin,
+		stdout:    opts.Stdout,
+		stderr:    opts.Stderr,
+		directory: opts.Dir,
+		env:       map[string]string{},
+		registry:  NewRegistry(),
+	}
+
+	if shell.in == nil {
+		shell.in = os.Stdin
+	}
+	if shell.stdout == nil {
+		shell.stdout = os.Stdout
+	}
+	if shell.stderr == nil {
+		shell.stderr = os.Stderr
+	}
+
+	env := opts.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	for _, item := range env {
+		key, value, ok := strings.Cut(item, "=")
+		if ok {
+			shell.env[key] = value
+		}
+	}
+
+	if shell.directory != "" {
+		shell.directory = shell.resolvePath(shell.directory)
+	}
+
+	return shell
+}
+
+// getenv reads a variable from the shell environment. A Shell that was not
+// created with New has no environment of its own and uses the process one.
+func (shell *Shell) getenv(key string) string {
+	value, _ := shell.lookupEnv(key)
+	return value
+}
+
+// lookupEnv is getenv that also reports whether the variable is set.
+func (shell *Shell) lookupEnv(key string) (string, bool) {
+	if shell.env == nil {
+		return os.LookupEnv(key)
+	}
+	value, ok := shell.env[key]
+	return value, ok
+}
+
+func (shell *Shell) setenv(key string, value string) {
+	if shell.env == nil {
+		shell.env = map[string]string{}
+		for _, item := range os.Environ() {
+			if k, v, ok := strings.Cut(item, "="); ok {
+				shell.env[k] = v
+			}
+		}
+	}
+	shell.env[key] = value
+
+	if key == "PATH" {
+		shell.hash.clear()
+	}
+}
+
+// Getenv returns the value of a variable in the shell environment.
+func (shell *Shell) Getenv(key string) string {
+	return shell.getenv(key)
+}
+
+// Setenv sets a variable in the shell environment, as NAME=value would.
+func (shell *Shell) Setenv(key string, value string) {
+	shell.setenv(key, value)
+}
+
+// assignment splits a NAME=value word, ok is false for anything else.
+func assignment(word string) (string, string, bool) {
+	name, value, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return "", "", false
+	}
+	for i, char := range name {
+		if char != '_' && !unicode.IsLetter(char) && (i == 0 || !unicode.IsDigit(char)) {
+			return "", "", false
+		}
+	}
+	return name, value, true
+}
+
+func (shell *Shell) environ() []string {
+	if shell.env == nil {
+		return os.Environ()
+	}
+
+	env := make([]string, 0, len(shell.env))
+	for key, value := range shell.env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+// Builtins returns the registry the shell resolves builtins from, changes to
+// it take effect on the next command.
+func (shell *Shell) Builtins() *Registry {
+	if shell.registry == nil {
+		shell.registry = NewRegistry()
+	}
+	return shell.registry
+}
+
+// Context returns the context of the command being run, builtins that may
+// block should give up once it is done.
+func (shell *Shell) Context() context.Context {
+	if shell.ctx == nil {
+		return context.Background()
+	}
+	return shell.ctx
+}
+
+func (shell *Shell) isBuiltinCommand(command Command) bool {
+	_, ok := shell.Builtins().Lookup(command)
+	return ok
+}
+
+func findFile(cwd string, directories string, file string) (bool, string) {
+	directoriesSplit := strings.Split(directories, ":")
+
+	for _, item := range directoriesSplit {
+		if item == "" {
+			item = "."
+		}
+		if !filepath.IsAbs(item) && cwd != "" {
+			item = filepath.Join(cwd, item)
+		}
+		path := filepath.Join(item, file)
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() && info.Mode().Perm()&0100 != 0 {
+			return true, path
+		}
+
+	}
+	return false, ""
+}
+
+// findAllFiles is findFile that keeps looking, it returns every match in order.
+func findAllFiles(cwd string, directories string, file string) []string {
+	paths := []string{}
+
+	for _, item := range strings.Split(directories, ":") {
+		if item == "" {
+			item = "."
+		}
+		if !filepath.IsAbs(item) && cwd != "" {
+			item = filepath.Join(cwd, item)
+		}
+		path := filepath.Join(item, file)
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() && info.Mode().Perm()&0100 != 0 {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// resolvePath makes path absolute against the shell's own working directory,
+// the process working directory is never consulted unless the shell has none.
+func (shell *Shell) resolvePath(path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Join(shell.workingDirectory(), path)
+}
+
+// homeDirectory is $HOME of the shell, or the home of the user running it.
+func (shell *Shell) homeDirectory() (string, error) {
+	if home := shell.getenv("HOME"); home != "" {
+		return home, nil
+	}
+	return os.UserHomeDir()
+}
+
+// tildeDirectory is the directory ~name stands for, $HOME for a bare ~ and
+// the home directory of the user name otherwise.
+func (shell *Shell) tildeDirectory(name string) (string, error) {
+	if name == "" {
+		return shell.homeDirectory()
+	}
+	account, err := user.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return account.HomeDir, nil
+}
+
+func (shell *Shell) workingDirectory() string {
+	if shell.directory == "" {
+		directory, err := os.Getwd()
+		if err != nil {
+			return "/"
+		}
+		shell.directory = directory
+	}
+
+	return shell.directory
+}
+
+// displayFilesFromDir lists the executable files in a PATH list of
+// directories, a symlink counts when what it points to is executable.
+func displayFilesFromDir(directories string) []string {
+	directoriesSplit := strings.Split(directories, ":")
+	allFiles := []string{}
+
+	for _, item := range directoriesSplit {
+		entries, err := os.ReadDir(item)
+
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			info, err := os.Stat(filepath.Join(item, entry.Name()))
+			if err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0 {
+				allFiles = append(allFiles, entry.Name())
+			}
+		}
+
+	}
+	return allFiles
+}
+
+func (shell *Shell) handleEchoCommand(args []string) (string, error) {
+	// the space before a redirection or a pipe is not part of the arguments
+	for len(args) > 0 && args[len(args)-1] == " " {
+		args = args[:len(args)-1]
+	}
+	return strings.Join(args, ""), nil
+}
+
+func (shell *Shell) handleExitCommand(args []string) (string, error) {
+	code := 0
+
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err == nil {
+			code = n
+		} else {
+			code = 1
+		}
+	}
+
+	return "", &ExitError{Code: code}
+}
+
+// lookCommand finds the file to run for command. A name with a slash is a
+// path relative to the working directory, other names are looked up in PATH.
+func (shell *Shell) lookCommand(command string) (string, error) {
+	if !strings.Contains(command, "/") {
+		path, ok := shell.findCommand(command)
+		if !ok {
+			return "", &statusError{status: 127, message: command + ": command not found"}
+		}
+		return path, nil
+	}
+
+	path := shell.resolvePath(command)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", &statusError{status: 127, message: command + ": No such file or directory"}
+	}
+	if info.IsDir() {
+		return "", &statusError{status: 126, message: command + ": Is a directory"}
+	}
+	if info.Mode().Perm()&0111 == 0 {
+		return "", &statusError{status: 126, message: command + ": Permission denied"}
+	}
+
+	return path, nil
+}
+
+// waitDelay is how long Wait keeps reading the output of a command that has
+// exited or was killed, a child left running with the pipes open does not
+// keep the shell waiting beyond it.
+const waitDelay = time.Second
+
+func (shell *Shell) handleExternalCommand(ctx context.Context, command Command, args []string) (*exec.Cmd, error) {
+	path, err := shell.lookCommand(string(command))
+
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Args[0] = string(command)
+	processGroup(cmd, 0, shell.terminal())
+	cmd.WaitDelay = waitDelay
+	cmd.Dir = shell.workingDirectory()
+	cmd.Env = shell.environ()
+
+	return cmd, nil
+}
+
+// ignoreWaitDelay drops the error of a command that succeeded but had its
+// output pipes closed after waitDelay, it is the children it left running
+// that held them.
+func ignoreWaitDelay(err error) error {
+	if errors.Is(err, exec.ErrWaitDelay) {
+		return nil
+	}
+	return err
+}
+
+// syncFunctionWrapper runs a builtin as a pipeline stage. It owns both ends it
+// is given and closes them when done, so the neighbouring stages see EOF.
+func (shell *Shell) syncFunctionWrapper(stage Pipes, args []string, fn func(shell *Shell, args []string) (string, error)) <-chan error {
+	done := make(chan error, 1)
+
+	go func() {
+		output, err := fn(shell, args)
+		if output != "" {
+			fmt.Fprintln(stage.write, output)
+		}
+
+		if err != nil && err.Error() != "" {
+			fmt.Fprintln(shell.stderr, err)
+		}
+		stage.close()
+		done <- err
+	}()
+
+	return done
+}
+
+// syncWriter serialises writes from the concurrent stages of a pipeline when
+// the shell writes to something that is not a file, such as a bytes.Buffer.
+type syncWriter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer.Write(p)
+}
+
+// Pipes are the ends a pipeline stage reads from and writes to. The write end
+// is closed with the stage only when it is a pipe, never the shell's stdout.
+type Pipes struct {
+	write     io.Writer
+	read      *os.File
+	ownsWrite bool
+}
+
+func (p Pipes) close() {
+	if p.read != nil {
+		p.read.Close()
+	}
+	if file, ok := p.write.(*os.File); ok && p.ownsWrite {
+		file.Close()
+	}
+}
+
+func (shell *Shell) pipeline(ctx context.Context, commands []ParsedCommand) error {
+	var rPrev *os.File
+
+	pipesIO := []Pipes{}
+
+	for i := 0; i < len(commands)-1; i++ {
+		r, w, err := os.Pipe()
+
+		if err != nil {
+			for _, stage := range pipesIO {
+				stage.close()
+			}
+			rPrev.Close()
+			return err
+		}
+		pipesIO = append(pipesIO, Pipes{write: w, read: rPrev, ownsWrite: true})
+
+		rPrev = r
+	}
+
+	var stdout io.Writer = shell.stdout
+	var stderr io.Writer = shell.stderr
+	if _, ok := stdout.(*os.File); !ok {
+		stdout = &syncWriter{writer: stdout}
+	}
+	if shell.stderr == shell.stdout {
+		stderr = stdout
+	} else if _, ok := stderr.(*os.File); !ok {
+		stderr = &syncWriter{writer: stderr}
+	}
+
+	pipesIO = append(pipesIO, Pipes{write: stdout, read: rPrev})
+
+	previousCtx, previousStderr := shell.ctx, shell.stderr
+	shell.ctx, shell.stderr = ctx, stderr
+	defer func() {
+		shell.ctx, shell.stderr = previousCtx, previousStderr
+	}()
+
+	// One result per stage, the status of the pipeline is the one of the last.
+	results := make([]func() error, len(commands))
+	// the commands of the pipeline share the process group of the first one
+	pgid := 0
+
+	for index, comamnd := range commands {
+		stage := pipesIO[index]
+		// a < of the stage replaces the pipe it would read
+		file, err := shell.inputFile(comamnd.Redirection)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			stage.close()
+			results[index] = func() error { return &statusError{status: 1} }
+			continue
+		}
+		if file != nil {
+			if stage.read != nil {
+				stage.read.Close()
+			}
+			stage.read = file
+		}
+		handlerFunc := shell.getHandleCommandRaw(comamnd.Command)
+		args := shell.filterArgs(comamnd.Command, comamnd.Arguments)
+		if shell.xtrace {
+			shell.trace(stderr, comamnd.Command, args)
+		}
+
+		if handlerFunc.SimpleHandler != nil {
+			target := shell
+			if _, ok := shell.functions[string(comamnd.Command)]; ok || stage.read != nil {
+				// a function changes the shell state and a builtin like
+				// timeout hands the input of the stage to the command it
+				// runs, in a pipeline they get a copy of the shell to do it
+				// in, as they would in a subshell
+				target = shell.subshell(stage.write)
+				target.ctx = ctx
+				target.stdin = stage.read
+			}
+			done := target.syncFunctionWrapper(stage, args, handlerFunc.SimpleHandler)
+			results[index] = func() error { return <-done }
+			continue
+		}
+
+		cmd, err := handlerFunc.CommandHandler(shell, ctx, comamnd.Command, args)
+		if err == nil {
+			if stage.read != nil {
+				cmd.Stdin = stage.read
+			}
+			cmd.Stdout = stage.write
+			cmd.Stderr = stderr
+			if pgid != 0 {
+				processGroup(cmd, pgid, nil)
+			}
+
+			err = cmd.Start()
+			if err == nil && pgid == 0 {
+				pgid = cmd.Process.Pid
+			}
+		}
+		if errors.Is(err, syscall.ENOEXEC) {
+			path := cmd.Path
+			done := shell.syncFunctionWrapper(stage, args, func(shell *Shell, args []string) (string, error) {
+				return shell.runScriptFile(ctx, path)
+			})
+			results[index] = func() error { return <-done }
+			continue
+		}
+		stage.close()
+
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			results[index] = func() error { return err }
+			continue
+		}
+		results[index] = func() error { return ignoreWaitDelay(cmd.Wait()) }
+	}
+
+	var err error
+	for _, result := range results {
+		err = result()
+	}
+	if pgid != 0 {
+		takeTerminal(shell.terminal())
+	}
+
+	return err
+}
+
+func (shell *Shell) handlePwdCommand(args []string) (string, error) {
+	return shell.workingDirectory(), nil
+}
+
+func (shell *Shell) handleCdCommand(args []string) (string, error) {
+	if len(args) > 1 {
+		return "", fmt.Errorf("expecting only 1 argument")
+	} else if len(args) == 0 {
+		return "", fmt.Errorf("missing argument")
+	}
+
+	goToPath := args[0]
+
+	if goToPath == "~" {
+		home, err := shell.homeDirectory()
+		if err != nil {
+			return "cd: error getting home directory", nil
+		}
+		goToPath = home
+	}
+
+	directory := shell.resolvePath(goToPath)
+	info, err := os.Stat(directory)
+	if err != nil {
+		return "", fmt.Errorf("%s", "cd: "+args[0]+": No such file or directory")
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s", "cd: "+args[0]+": Not a directory")
+	}
+	shell.directory = directory
+
+	return "", nil
+}
+
+// filterParams turns parsed arguments into words, " " separates them and the
+// pieces of a single word, like test”shell, are joined together.
+func filterParams(args []string) []string {
+	newArgs := []string{}
+	word := ""
+	inWord := false
+	for _, item := range args {
+		if item == " " {
+			if inWord {
+				newArgs = append(newArgs, word)
+			}
+			word = ""
+			inWord = false
+			continue
+		}
+		word += item
+		inWord = true
+	}
+	if inWord {
+		newArgs = append(newArgs, word)
+	}
+
+	return newArgs
+}
+
+type CommandSpecResponse struct {
+	SimpleHandler  func(shell *Shell, args []string) (string, error)
+	CommandHandler func(shell *Shell, ctx context.Context, command Command, args []string) (*exec.Cmd, error)
+}
+
+func (shell *Shell) getHandleCommandRaw(command Command) CommandSpecResponse {
+	if function, ok := shell.functions[string(command)]; ok {
+		return CommandSpecResponse{
+			SimpleHandler: func(shell *Shell, args []string) (string, error) {
+				return shell.callFunction(function, args)
+			},
+		}
+	}
+
+	return shell.getBuiltinOrExternal(command)
+}
+
+// getBuiltinOrExternal resolves command without looking at functions, which
+// is what the command builtin runs.
+func (shell *Shell) getBuiltinOrExternal(command Command) CommandSpecResponse {
+	data, ok := shell.Builtins().Lookup(command)
+	if ok {
+		return CommandSpecResponse{
+			SimpleHandler:  builtinHandler(data),
+			CommandHandler: nil,
+		}
+	}
+
+	return CommandSpecResponse{
+		SimpleHandler:  nil,
+		CommandHandler: (*Shell).handleExternalCommand,
+	}
+}
+
+func (shell *Shell) filterArgs(command Command, args []string) []string {
+	if spec, ok := shell.Builtins().Lookup(command); !(ok && spec.NeedsRawArgs) {
+		args = filterParams(args)
+
+	}
+
+	return args
+}
+
+func (shell *Shell) handleCommand(ctx context.Context, command Command, rawArgs []string) (string, error) {
+	return shell.runHandler(ctx, command, shell.getHandleCommandRaw(command), rawArgs)
+}
+
+func (shell *Shell) runHandler(ctx context.Context, command Command, handlerFunc CommandSpecResponse, rawArgs []string) (string, error) {
+	args := shell.filterArgs(command, rawArgs)
+	if shell.xtrace {
+		shell.trace(shell.stderr, command, args)
+	}
+	if handlerFunc.SimpleHandler != nil {
+		previous := shell.ctx
+		shell.ctx = ctx
+		defer func() {
+			shell.ctx = previous
+		}()
+		return handlerFunc.SimpleHandler(shell, args)
+	} else if handlerFunc.CommandHandler == nil {
+		panic("Never should happend that command handler is nill when simpler handler inill too")
+	}
+
+	cmd, err := handlerFunc.CommandHandler(shell, ctx, command, args)
+
+	if err != nil {
+		return "", err
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd.Stdin = shell.stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = ignoreWaitDelay(cmd.Run())
+	takeTerminal(shell.terminal())
+	if errors.Is(err, syscall.ENOEXEC) {
+		return shell.runScriptFile(ctx, cmd.Path)
+	}
+	clean := bytes.Trim(stdout.Bytes(), "\x00")
+	output := string(clean)
+	output = strings.TrimRight(output, "\n")
+
+	if err != nil && ctx.Err() != nil {
+		return output, &statusError{status: 137, message: string(command) + ": " + ctx.Err().Error()}
+	}
+	if err != nil {
+		result := string(stderr.String())
+		result = strings.TrimRight(result, "\n")
+		return output, &statusError{status: cmd.ProcessState.ExitCode(), message: result}
+	}
+	if len(stderr.Bytes()) > 0 {
+		return "", fmt.Errorf("%s", stderr.String())
+	}
+
+	return output, nil
+}
+
+// inputFile opens the file of the last < in the redirections args, it is nil
+// when there is none.
+func (shell *Shell) inputFile(args []string) (*os.File, error) {
+	var file *os.File
+	for i := 0; i+1 < len(args); i += 2 {
+		if args[i] != "<" {
+			continue
+		}
+		if file != nil {
+			file.Close()
+		}
+
+		var err error
+		file, err = os.Open(shell.resolvePath(args[i+1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s", fmt.Sprintf("err opening file, err: %v", err))
+		}
+	}
+	return file, nil
+}
+
+// redirect applies the output redirections of args, < is left to inputFile.
+func (shell *Shell) redirect(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	if len(args) == 1 {
+		return "", fmt.Errorf("missing redirection dest")
+	}
+
+	operator := args[0]
+	if operator == "<" {
+		return shell.redirect(args[2:])
+	}
+
+	if operator != ">" && operator != "1>" && operator != "2>" && operator != ">>" && operator != "1>>" && operator != "2>>" {
+		return "", fmt.Errorf("not supported redirection")
+	}
+
+	append := strings.Contains(operator, ">>")
+
+	outputFile := shell.resolvePath(args[1])
+	var file *os.File
+	var err error
+
+	if append {
+		file, err = os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	} else {
+		file, err = os.Create(outputFile)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("%s", fmt.Sprintf("err creating file, err: %v", err))
+	}
+	if operator == "2>" || operator == "2>>" {
+		shell.stderr = file
+	} else {
+		shell.stdout = file
+	}
+	return "", nil
+
+}
+
+// Eval parses and runs a single command line. The returned status is the exit
+// status of the line; command failures are reported on stderr and only a
+// parse error or the exit builtin (as *ExitError) is returned as an error.
+func (shell *Shell) Eval(line string) (int, error) {
+	return shell.EvalContext(context.Background(), line)
+}
+
+// EvalContext is Eval with a context, cancelling it kills the running
+// command together with the processes it started.
+func (shell *Shell) EvalContext(ctx context.Context, line string) (int, error) {
+	if strings.TrimSpace(line) == "" {
+		return 0, nil
+	}
+
+	parser := NewParser(shell.expandAliases(line))
+	list, err := parser.ParseList()
+	if err != nil {
+		return 2, err
+	}
+
+	return shell.runList(ctx, list)
+}
+
+// runList runs pipelines joined by ";", "&&" and "||". A pipeline after
+// "&&" runs only when the status so far is 0, after "||" only when it is not.
+func (shell *Shell) runList(ctx context.Context, list []ParsedPipeline) (int, error) {
+	status := 0
+
+	for _, pipeline := range list {
+		if ctx.Err() != nil {
+			break
+		}
+		if (pipeline.Operator == "&&" && status != 0) || (pipeline.Operator == "||" && status == 0) {
+			continue
+		}
+
+		code, err := shell.runPipeline(ctx, pipeline)
+		if err != nil {
+			return code, err
+		}
+		status = code
+	}
+
+	return status, nil
+}
+
+func (shell *Shell) runPipeline(ctx context.Context, pipeline ParsedPipeline) (int, error) {
+	if pipeline.Function != nil {
+		if shell.functions == nil {
+			shell.functions = map[string]*ParsedFunction{}
+		}
+		shell.functions[pipeline.Function.Name] = pipeline.Function
+		return 0, nil
+	}
+
+	commands := pipeline.Commands
+	if len(commands) > 1 {
+		err := shell.pipeline(ctx, commands)
+		return exitStatus(err), nil
+	}
+
+	input := commands[0]
+
+	if name, value, ok := assignment(string(input.Command)); ok && len(filterParams(input.Arguments)) == 0 {
+		shell.setenv(name, value)
+		return 0, nil
+	}
+
+	stdin := shell.stdin
+	stdout := shell.stdout
+	stderr := shell.stderr
+	defer func() {
+		for _, writer := range []io.Writer{shell.stdout, shell.stderr} {
+			if file, ok := writer.(*os.File); ok && writer != stdout && writer != stderr {
+				file.Close()
+			}
+		}
+		if file, ok := shell.stdin.(*os.File); ok && shell.stdin != stdin {
+			file.Close()
+		}
+		shell.stdin = stdin
+		shell.stdout = stdout
+		shell.stderr = stderr
+	}()
+
+	file, err := shell.inputFile(input.Redirection)
+	if err == nil {
+		if file != nil {
+			shell.stdin = file
+		}
+		_, err = shell.redirect(input.Redirection)
+	}
+	if err != nil {
+		fmt.Fprintln(shell.stderr, err)
+		return 1, nil
+	}
+
+	output, err := shell.handleCommand(ctx, input.Command, input.Arguments)
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code, err
+	}
+	if err != nil && err.Error() != "" {
+		fmt.Fprintln(shell.stderr, err)
+	}
+
+	if output != "" {
+		fmt.Fprintln(shell.stdout, output)
+	}
+
+	return exitStatus(err), nil
+}
+
+// maxCallDepth is how deep functions may call each other, so that a function
+// calling itself fails instead of exhausting the stack.
+const maxCallDepth = 200
+
+// callFunction runs the body of function with its output captured, the way
+// builtins return theirs. The arguments are accepted, but there are no
+// positional parameters to read them with yet.
+func (shell *Shell) callFunction(function *ParsedFunction, args []string) (string, error) {
+	if shell.callDepth >= maxCallDepth {
+		return "", fmt.Errorf("%s: maximum function nesting level exceeded (%d)", function.Name, maxCallDepth)
+	}
+
+	var stdout bytes.Buffer
+	saved := shell.stdout
+	shell.stdout = &stdout
+	shell.callDepth++
+	status, err := shell.runList(shell.Context(), function.Body)
+	shell.callDepth--
+	shell.stdout = saved
+
+	output := strings.TrimRight(stdout.String(), "\n")
+	if err != nil {
+		return output, err
+	}
+	if status != 0 {
+		return output, &statusError{status: status}
+	}
+
+	return output, nil
+}
+
+// Run executes script command by command and returns the status of the last
+// one, or the code passed to exit. A command goes on in the next line the way
+// it does at the prompt, blank lines and # comments are skipped.
+func (shell *Shell) Run(ctx context.Context, script string) (int, error) {
+	status := 0
+	command := ""
+
+	lines := strings.Split(script, "\n")
+	for i, line := range lines {
+		if err := ctx.Err(); err != nil {
+			return 130, err
+		}
+		if command == "" && strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		// an unfinished command at the end of the script is run as it is,
+		// the parser reports what is missing
+		var more bool
+		command, more = continueCommand(command, line)
+		if more && i < len(lines)-1 {
+			continue
+		}
+
+		code, err := shell.EvalContext(ctx, command)
+		command = ""
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code, nil
+		}
+		if err != nil {
+			return code, err
+		}
+		status = code
+	}
+
+	return status, nil
+}
+
+// RunInteractive reads commands with line editing until exit, end of input
+// or ctx is cancelled. History is loaded from $HISTFILE and the commands of
+// the session are appended to it on exit.
+func (shell *Shell) RunInteractive(ctx context.Context) (int, error) {
+	if histFile := shell.getenv("HISTFILE"); histFile != "" {
+		err := shell.readHistory(shell.resolvePath(histFile), false)
+		if err != nil && !os.IsNotExist(err) {
+			return 1, err
+		}
+	}
+
+	_, exitCode := shell.readLoop(ctx)
+
+	if histFile := shell.getenv("HISTFILE"); histFile != "" {
+		if err := shell.appendHistory(shell.resolvePath(histFile)); err != nil {
+			return 1, err
+		}
+	}
+
+	return exitCode, nil
+}
+
+func (shell *Shell) startCli() (bool, int) {
+	return shell.readLoop(context.Background())
+}
+
+// terminal is the file the shell reads its commands from when it is a
+// terminal, and nil when it is not.
+func (shell *Shell) terminal() *os.File {
+	if file, ok := shell.in.(*os.File); ok && readline.IsTerminal(int(file.Fd())) {
+		return file
+	}
+	return nil
+}
+
+// terminalWidth is the width of the terminal, or $COLUMNS or 80 when there
+// is none. The line editor divides by it, so it is never zero.
+func (shell *Shell) terminalWidth() int {
+	if width := readline.GetScreenWidth(); width > 0 {
+		return width
+	}
+	if columns, err := strconv.Atoi(shell.getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return 80
+}
+
+// continueCommand adds line to the command read so far and reports whether
+// the command goes on in the next line: a quote is still open, the line ends
+// with a backslash or a { has not been closed. Inside braces the lines are
+// joined with "; ", unless the line already ends in an operator or a {.
+func continueCommand(command string, line string) (string, bool) {
+	command += line
+
+	switch {
+	case openQuote(command) != 0:
+		return command + "\n", true
+	case strings.HasSuffix(command, "\\") && (len(command)-len(strings.TrimRight(command, "\\")))%2 == 1:
+		return command[:len(command)-1], true
+	case openBraces(command) > 0:
+		trimmed := strings.TrimRight(command, " \t")
+		if strings.ContainsAny(trimmed[len(trimmed)-1:], "{|&;") {
+			return trimmed + " ", true
+		}
+		return trimmed + "; ", true
+	}
+
+	return command, false
+}
+
+// openBraces counts the { words in command position that have no } yet.
+func openBraces(command string) int {
+	depth := 0
+	lexar := newLexar(command)
+	commandPosition := true
+
+	for token := lexar.nextToken(); token.tokenType != EOF; token = lexar.nextToken() {
+		switch token.tokenType {
+		case PIPE, LIST:
+			commandPosition = true
+		case STRING:
+			unquoted := command[token.pos:lexar.i] == token.literal
+			switch {
+			case commandPosition && unquoted && token.literal == "{":
+				depth++
+			case commandPosition && unquoted && token.literal == "}" && depth > 0:
+				depth--
+			}
+			// the body of name() { starts in command position
+			commandPosition = token.literal == "{" || token.literal == ")"
+		case REDIRECT:
+			commandPosition = false
+		}
+	}
+
+	return depth
+}
+
+// readCommand reads a command line with $PS1 as the prompt, and the lines
+// that continue it with $PS2 as long as continueCommand asks for more. The
+// lines of a multi-line prompt but the last are printed before it, the line
+// editor only redraws the last one.
+func (shell *Shell) readCommand(l *readline.Instance) (string, error) {
+	prompt := shell.prompt("PS1")
+	line := ""
+	for {
+		if index := strings.LastIndex(prompt, "\n"); index >= 0 {
+			if l.Config.FuncIsTerminal() {
+				fmt.Fprint(l.Stdout(), prompt[:index+1])
+			}
+			prompt = prompt[index+1:]
+		}
+		l.SetPrompt(prompt)
+
+		raw, err := l.Readline()
+		if err != nil {
+			return "", err
+		}
+		var more bool
+		line, more = continueCommand(line, raw)
+		if !more {
+			return line, nil
+		}
+		prompt = shell.prompt("PS2")
+	}
+}
+
+func (shell *Shell) readLoop(ctx context.Context) (bool, int) {
+	navigator := &historyNavigator{}
+	completer := &AutoComplete{shell: shell}
+	l, err := readline.NewEx(&readline.Config{
+		Stdin:        io.NopCloser(shell.in),
+		Stdout:       shell.stdout,
+		Stderr:       shell.stderr,
+		AutoComplete: completer,
+		Listener:     navigator,
+		// the history comes from shell.history before every prompt
+		DisableAutoSaveHistory: true,
+		HistoryLimit:           math.MaxInt32,
+		FuncGetWidth:           shell.terminalWidth,
+	})
+	if err != nil {
+		return true, 0
+	}
+	defer l.Close()
+	completer.readline = l
+	shell.databaseLines = shell.historyDatabaseLines()
+
+	stop := context.AfterFunc(ctx, func() {
+		l.Close()
+	})
+	defer stop()
+
+	for {
+		if histFile := shell.getenv("HISTFILE"); histFile != "" && shell.getenv("HISTSHARE") != "" {
+			if err := shell.shareHistory(shell.resolvePath(histFile)); err != nil {
+				fmt.Fprintln(shell.stderr, err)
+			}
+		}
+
+		shell.loadEditorHistory(l, navigator)
+
+		raw, err := shell.readCommand(l)
+		if err != nil || ctx.Err() != nil {
+			return false, 0
+		}
+
+		expansion, err := shell.expandHistory(raw)
+		if err != nil {
+			fmt.Fprintln(shell.stderr, err)
+			continue
+		}
+		if expansion.expanded {
+			fmt.Fprintln(shell.stdout, expansion.line)
+		}
+
+		stored := shell.addHistory(expansion.line)
+		if expansion.printOnly {
+			continue
+		}
+
+		if stored {
+			shell.currentLine = expansion.line
+		}
+		cwd, start := shell.workingDirectory(), time.Now()
+		status, err := shell.EvalContext(ctx, expansion.line)
+		shell.currentLine = ""
+		var exitErr *ExitError
+		if errors.As(err, &exitErr) {
+			status = exitErr.Code
+		}
+		shell.lastStatus = status
+		if stored {
+			if err := shell.recordHistory(expansion.line, cwd, start, status); err != nil {
+				fmt.Fprintln(shell.stderr, err)
+			}
+		}
+		if exitErr != nil {
+			return true, exitErr.Code
+		}
+		if err != nil {
+			fmt.Fprintln(shell.stderr, err)
+			return true, 1
+		}
+	}
+}
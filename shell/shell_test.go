@@ -0,0 +1,589 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInvalidCommand(t *testing.T) {
+	input := strings.NewReader("aa\nexit\n")
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := Shell{
+		in:     input,
+		stdout: &output,
+		stderr: &errout,
+	}
+
+	shell.startCli()
+	got := errout.String()
+	fmt.Println(output.String())
+	expectedResult := "aa: command not found"
+
+	if !strings.Contains(got, expectedResult) {
+		t.Errorf("Expected to contain message: %q, got: %q", expectedResult, got)
+	}
+}
+
+func TestValidCommand(t *testing.T) {
+	input := strings.NewReader("echo\n")
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := Shell{
+		in:     input,
+		stdout: &output,
+		stderr: &errout,
+	}
+
+	shell.startCli()
+
+	got := output.String()
+
+	if strings.Contains(got, "command not found") {
+		t.Errorf("Expected command to be found got: %v", got)
+	}
+}
+
+func TestExitCommand(t *testing.T) {
+
+	input := strings.NewReader("exit 0\n")
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := Shell{
+		in:     input,
+		stdout: &output,
+		stderr: &errout,
+	}
+
+	exitRequest, exitCode := shell.startCli()
+
+	if exitRequest == false || exitCode != 0 {
+		t.Errorf("Expect to exit with code 0 insttead got existRequest: %t, and code: %d", exitRequest, exitCode)
+	}
+}
+
+func getRawOutput(text string) string {
+	withoutDollar := strings.ReplaceAll(text, "$ ", "")
+
+	trimed := strings.TrimSpace(withoutDollar)
+
+	return trimed
+}
+
+func TestEchoCommand(t *testing.T) {
+
+	input := strings.NewReader("echo abc def\n")
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := Shell{
+		in:     input,
+		stdout: &output,
+		stderr: &errout,
+	}
+
+	shell.startCli()
+	got := getRawOutput(output.String())
+	expectedResult := "abc def"
+
+	if got != expectedResult {
+		t.Errorf("Expected result to be %q, got: %q", expectedResult, got)
+	}
+}
+
+type Case struct {
+	input  string
+	output string
+	name   string
+	err    string
+	setup  func() string
+	clear  func()
+}
+
+func TestTypeCommand(t *testing.T) {
+	cases := []Case{
+		{input: "type echo", output: "echo is a shell builtin", name: "valid command type echo"},
+		{input: "type abc", err: "abc: not found", output: "", name: "invalid command type abc"},
+		{input: "type script", output: "script is /tmp/123/test2/script", name: "valid not builtin command",
+			setup: func() string {
+				os.Mkdir("/tmp/123", 0755)
+				dir := "/tmp/123"
+				fmt.Println(dir)
+				os.Setenv("PATH", dir+"/test1"+":"+dir+"/test2")
+				os.Mkdir(dir+"/test1", 0755)
+				os.Mkdir(dir+"/test2", 0755)
+				os.WriteFile(filepath.Join(dir+"/test1", "script"), []byte("fsdfs"), 0644)
+				os.WriteFile(filepath.Join(dir+"/test2", "script"), []byte("fsdfs"), 0755)
+
+				return dir
+			},
+			clear: func() {
+				os.RemoveAll("/tmp/123")
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.setup != nil {
+				testCase.setup()
+			}
+			input := strings.NewReader(testCase.input + "\n")
+
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := Shell{
+				in:     input,
+				stdout: &output,
+				stderr: &errout,
+			}
+
+			shell.startCli()
+			got := getRawOutput(output.String())
+			expected := testCase.output
+			if testCase.output == "" {
+				got = getRawOutput(errout.String())
+				expected = testCase.err
+			}
+
+			if got != expected {
+				t.Errorf("Expected result to be %q, got: %q", expected, got)
+			}
+
+			if testCase.clear != nil {
+				testCase.clear()
+			}
+		})
+	}
+
+}
+
+func TestPwdCommand(t *testing.T) {
+
+	input := strings.NewReader("pwd\n")
+
+	var output bytes.Buffer
+	path, _ := os.Getwd()
+	shell := Shell{
+		in:        input,
+		stdout:    &output,
+		directory: path,
+	}
+
+	shell.startCli()
+	got := getRawOutput(output.String())
+	expectedResult := path
+
+	if got != expectedResult {
+		t.Errorf("Expected result to be %q, got: %q", expectedResult, got)
+	}
+}
+
+func TestChangeDirectory(t *testing.T) {
+	directory := t.TempDir()
+	os.MkdirAll(filepath.Join(directory, "tmp/123"), 0755)
+	processDirectory, _ := os.Getwd()
+
+	input := strings.NewReader("cd tmp/123\npwd\n")
+
+	var output bytes.Buffer
+	shell := Shell{
+		in:        input,
+		stdout:    &output,
+		directory: directory,
+	}
+
+	shell.startCli()
+	got := getRawOutput(output.String())
+	expectedResult := directory + "/tmp/123"
+
+	if !strings.Contains(got, expectedResult) {
+		t.Errorf("expected to print directory %v, got: %v", expectedResult, got)
+	}
+
+	if current, _ := os.Getwd(); current != processDirectory {
+		t.Errorf("expected process directory to stay %v, got: %v", processDirectory, current)
+	}
+}
+
+func TestChangeDirectoryPerShell(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	os.Mkdir(filepath.Join(first, "a"), 0755)
+	os.Mkdir(filepath.Join(second, "b"), 0755)
+
+	cases := []struct {
+		directory string
+		input     string
+		expected  string
+	}{
+		{directory: first, input: "cd a\npwd\n", expected: filepath.Join(first, "a")},
+		{directory: second, input: "cd b\npwd\n", expected: filepath.Join(second, "b")},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.directory, func(t *testing.T) {
+			t.Parallel()
+			var output bytes.Buffer
+			shell := Shell{
+				in:        strings.NewReader(testCase.input),
+				stdout:    &output,
+				directory: testCase.directory,
+			}
+
+			shell.startCli()
+			got := getRawOutput(output.String())
+
+			if got != testCase.expected {
+				t.Errorf("expected to print directory %v, got: %v", testCase.expected, got)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+
+	input := strings.NewReader("echo example\\ \\ \\ \\ \\ \\ hello\n")
+
+	var output bytes.Buffer
+	path, _ := os.Getwd()
+	shell := Shell{
+		in:        input,
+		stdout:    &output,
+		directory: path,
+	}
+
+	shell.startCli()
+	got := getRawOutput(output.String())
+	expectedResult := "example      hello"
+
+	if !strings.Contains(got, expectedResult) {
+		t.Errorf("expected to be %v, got: %v", expectedResult, got)
+	}
+
+	os.RemoveAll("tmp")
+}
+
+func TestSingleQuoteWithEscape(t *testing.T) {
+
+	input := strings.NewReader("echo 'example\"helloshell\"test'")
+
+	var output bytes.Buffer
+	path, _ := os.Getwd()
+	shell := Shell{
+		in:        input,
+		stdout:    &output,
+		directory: path,
+	}
+
+	shell.startCli()
+	got := getRawOutput(output.String())
+	expectedResult := "example\"helloshell\"test"
+
+	if !strings.Contains(got, expectedResult) {
+		t.Errorf("expected to be %v, got: %v", expectedResult, got)
+	}
+
+	os.RemoveAll("tmp")
+}
+
+func TestBackslashWithinDoubleQuotes(t *testing.T) {
+
+	input := strings.NewReader("echo \"hello'test'\\\\'script\"")
+
+	var output bytes.Buffer
+	path, _ := os.Getwd()
+	shell := Shell{
+		in:        input,
+		stdout:    &output,
+		directory: path,
+	}
+
+	shell.startCli()
+	got := getRawOutput(output.String())
+	expectedResult := "hello'test'\\'script"
+
+	if !strings.Contains(got, expectedResult) {
+		t.Errorf("expected to be %v, got: %v", expectedResult, got)
+	}
+
+	os.RemoveAll("tmp")
+}
+
+func TestRedirectOutput(t *testing.T) {
+
+	input := strings.NewReader("echo abcd > aa.txt")
+
+	var output bytes.Buffer
+	path := t.TempDir()
+	shell := Shell{
+		in:        input,
+		stdout:    &output,
+		directory: path,
+	}
+
+	shell.startCli()
+	outputFile, err := os.ReadFile(filepath.Join(path, "aa.txt"))
+	if err != nil {
+		t.Error(err)
+	}
+	cliOtput := getRawOutput(output.String())
+	fmt.Println("cliout", cliOtput)
+	got := string(outputFile)
+	expectedResult := "abcd"
+
+	if !strings.Contains(got, expectedResult) {
+		t.Errorf("expected to be %v, got: %v", expectedResult, got)
+	}
+
+	defer func() {
+		if file, ok := shell.stdout.(*os.File); ok {
+			file.Close()
+		}
+	}()
+}
+
+func TestRedirectOutput2(t *testing.T) {
+
+	os.Mkdir("/tmp/foo", 0755)
+	os.Mkdir("/tmp/bar", 0755)
+	os.Setenv("PATH", "/usr/bin")
+
+	os.WriteFile("/tmp/foo/apple", []byte("apple"), 0755)
+	os.WriteFile("/tmp/foo/blueberry", []byte("blueberry"), 0755)
+	os.WriteFile("/tmp/foo/mango", []byte("mango"), 0755)
+
+	input := strings.NewReader("ls -1 /tmp/foo > /tmp/bar/bar.md\n")
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	path, _ := os.Getwd()
+	shell := Shell{
+		in:        input,
+		stdout:    &output,
+		stderr:    &errout,
+		directory: path,
+	}
+
+	shell.startCli()
+	outputFile, err := os.ReadFile("/tmp/bar/bar.md")
+	if err != nil {
+		t.Error(err)
+	}
+	cliOtput := getRawOutput(output.String())
+	fmt.Println("cliout", cliOtput)
+	got := string(outputFile)
+	expectedResult := "apple\nblueberry\nmango"
+
+	if !strings.Contains(got, expectedResult) {
+		t.Errorf("expected to be %v, got: %v", expectedResult, got)
+	}
+	defer func() {
+		if file, ok := shell.stdout.(*os.File); ok {
+			file.Close()
+		}
+	}()
+}
+
+func TestRunScript(t *testing.T) {
+	directory := t.TempDir()
+	os.Mkdir(filepath.Join(directory, "sub"), 0755)
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{
+		Stdout: &output,
+		Stderr: &errout,
+		Env:    []string{"PATH=/usr/bin:/bin"},
+		Dir:    directory,
+	})
+
+	code, err := shell.Run(context.Background(), "# comment\necho one\ncd sub\npwd\nexit 3\necho never\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != 3 {
+		t.Errorf("Expected exit code 3, got: %d", code)
+	}
+
+	expected := "one\n" + filepath.Join(directory, "sub") + "\n"
+	if output.String() != expected {
+		t.Errorf("Expected result to be %q, got: %q", expected, output.String())
+	}
+}
+
+func TestEvalStatus(t *testing.T) {
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{
+		Stdout: &output,
+		Stderr: &errout,
+		Env:    []string{"PATH=/usr/bin:/bin"},
+		Dir:    t.TempDir(),
+	})
+
+	cases := []struct {
+		line   string
+		status int
+	}{
+		{line: "echo ok", status: 0},
+		{line: "false", status: 1},
+		{line: "nosuchcommand", status: 127},
+		{line: "printf abc | wc -c", status: 0},
+	}
+
+	for _, testCase := range cases {
+		status, err := shell.Eval(testCase.line)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", testCase.line, err)
+		}
+		if status != testCase.status {
+			t.Errorf("%q: expected status %d, got: %d", testCase.line, testCase.status, status)
+		}
+	}
+
+	if !strings.Contains(output.String(), "3") {
+		t.Errorf("Expected pipeline output in stdout, got: %q", output.String())
+	}
+}
+
+func TestEnvIsPerShell(t *testing.T) {
+	var output bytes.Buffer
+	shell := New(Options{
+		Stdout: &output,
+		Stderr: &output,
+		Env:    []string{"PATH=/usr/bin:/bin", "GREETING=hello"},
+	})
+
+	shell.Eval("printenv GREETING")
+
+	if got := strings.TrimSpace(output.String()); got != "hello" {
+		t.Errorf("Expected result to be %q, got: %q", "hello", got)
+	}
+}
+
+func TestPipelineWithBuiltin(t *testing.T) {
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"PATH=/usr/bin:/bin"}})
+
+	status, _ := shell.Eval("echo hello | wc -c")
+	if status != 0 || strings.TrimSpace(output.String()) != "6" {
+		t.Errorf("Expected builtin output to reach wc, got status %d and %q", status, output.String())
+	}
+
+	output.Reset()
+	status, _ = shell.Eval("printf 'a\\nb\\n' | type echo")
+	if status != 0 || output.String() != "echo is a shell builtin\n" {
+		t.Errorf("Expected builtin at the end of pipeline, got status %d and %q", status, output.String())
+	}
+}
+
+func TestCommandByPath(t *testing.T) {
+	directory := t.TempDir()
+	// without a "#!" line it is run by the shell itself
+	os.WriteFile(filepath.Join(directory, "script"), []byte("echo built\nexit 3\n"), 0755)
+	os.WriteFile(filepath.Join(directory, "noexec"), []byte("#!/bin/sh\necho no\n"), 0644)
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"PATH=/usr/bin:/bin"}, Dir: directory})
+
+	if status, _ := shell.Eval("/bin/sh -c 'echo absolute'"); status != 0 || output.String() != "absolute\n" {
+		t.Errorf("Expected an absolute path to run, got status %d and %q", status, output.String())
+	}
+
+	output.Reset()
+	if status, _ := shell.Eval("./script"); status != 3 || output.String() != "built\n" {
+		t.Errorf("Expected a relative path to run, got status %d and %q", status, output.String())
+	}
+
+	if status, _ := shell.Eval("./noexec"); status != 126 || errout.String() != "./noexec: Permission denied\n" {
+		t.Errorf("Expected status 126, got %d and %q", status, errout.String())
+	}
+
+	errout.Reset()
+	if status, _ := shell.Eval("./missing"); status != 127 || errout.String() != "./missing: No such file or directory\n" {
+		t.Errorf("Expected status 127, got %d and %q", status, errout.String())
+	}
+}
+
+func TestCommandLists(t *testing.T) {
+	cases := []Case{
+		{input: "echo a; echo b", output: "a\nb", name: "sequence"},
+		{input: "true && echo yes", output: "yes", name: "and after success"},
+		{input: "false && echo yes", output: "", name: "and after failure"},
+		{input: "false || echo no", output: "no", name: "or after failure"},
+		{input: "false && echo yes || echo no", output: "no", name: "status carries over skipped pipeline"},
+		{input: "greet() { echo hi; echo there; }; greet", output: "hi\nthere", name: "function"},
+		{input: "greet() { echo hi; }; greet | wc -l", output: "1", name: "function in pipeline"},
+		{input: "loop() { loop; }; loop", output: "", name: "function recursion is limited"},
+		{input: "FOO='a b'; printenv FOO", output: "a b", name: "quoted assignment"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"PATH=/usr/bin:/bin"}})
+
+			shell.Eval(testCase.input)
+
+			if got := strings.TrimSpace(output.String()); got != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q (stderr %q)", testCase.output, got, errout.String())
+			}
+		})
+	}
+}
+
+func TestRunContinuedCommands(t *testing.T) {
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"PATH=/usr/bin:/bin"}})
+
+	script := "echo 'a\nb'\necho one \\\ntwo\ngreet() {\n  echo hi\n  echo there | cat\n}\ngreet\necho after\n"
+	if _, err := shell.Run(context.Background(), script); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "a\nb\none two\nhi\nthere\nafter\n"
+	if output.String() != expected || errout.Len() != 0 {
+		t.Errorf("Expected result to be %q, got: %q (stderr %q)", expected, output.String(), errout.String())
+	}
+}
+
+func TestInputRedirection(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "in.txt"), []byte("hello\n"), 0644)
+
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &errout, Dir: dir, Env: []string{"PATH=/usr/bin:/bin"}})
+
+	if status, _ := shell.Eval("cat < in.txt"); status != 0 || output.String() != "hello\n" {
+		t.Errorf("Expected cat to read the file, got status %d and %q", status, output.String())
+	}
+
+	output.Reset()
+	if status, _ := shell.Eval("cat < in.txt | tr a-z A-Z"); status != 0 || output.String() != "HELLO\n" {
+		t.Errorf("Expected the first stage to read the file, got status %d and %q", status, output.String())
+	}
+
+	output.Reset()
+	if status, _ := shell.Eval("echo ignored | cat < in.txt"); status != 0 || output.String() != "hello\n" {
+		t.Errorf("Expected the file to replace the pipe, got status %d and %q", status, output.String())
+	}
+
+	if status, _ := shell.Eval("cat < missing.txt"); status != 1 || errout.Len() == 0 {
+		t.Errorf("Expected a missing file to fail, got status %d and %q", status, errout.String())
+	}
+}
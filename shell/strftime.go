@@ -0,0 +1,89 @@
+package shell
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// strftimeLayouts maps the strftime conversions that have a time.Format
+// equivalent.
+var strftimeLayouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'B': "January",
+	'c': "Mon Jan _2 15:04:05 2006",
+	'd': "02",
+	'D': "01/02/06",
+	'e': "_2",
+	'F': "2006-01-02",
+	'h': "Jan",
+	'H': "15",
+	'I': "03",
+	'm': "01",
+	'M': "04",
+	'p': "PM",
+	'r': "03:04:05 PM",
+	'R': "15:04",
+	'S': "05",
+	'T': "15:04:05",
+	'x': "01/02/06",
+	'X': "15:04:05",
+	'y': "06",
+	'Y': "2006",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+// strftime formats t like strftime(3), which is what $HISTTIMEFORMAT uses.
+// Unknown conversions are copied as they are.
+func strftime(format string, t time.Time) string {
+	var builder strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			builder.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		conversion := format[i]
+		if layout, ok := strftimeLayouts[conversion]; ok {
+			builder.WriteString(t.Format(layout))
+			continue
+		}
+
+		switch conversion {
+		case '%':
+			builder.WriteByte('%')
+		case 'n':
+			builder.WriteByte('\n')
+		case 't':
+			builder.WriteByte('\t')
+		case 'j':
+			builder.WriteString(t.Format("002"))
+		case 'k':
+			builder.WriteString(padNumber(t.Hour()))
+		case 'l':
+			builder.WriteString(padNumber((t.Hour()+11)%12 + 1))
+		case 's':
+			builder.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'u':
+			builder.WriteString(strconv.Itoa((int(t.Weekday())+6)%7 + 1))
+		case 'w':
+			builder.WriteString(strconv.Itoa(int(t.Weekday())))
+		default:
+			builder.WriteByte('%')
+			builder.WriteByte(conversion)
+		}
+	}
+	return builder.String()
+}
+
+// padNumber is a two column number padded with a space, as %k and %l are.
+func padNumber(n int) string {
+	if n < 10 {
+		return " " + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
@@ -0,0 +1,25 @@
+package shell
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStrftime(t *testing.T) {
+	stamp := time.Date(2024, time.March, 5, 7, 8, 9, 0, time.UTC)
+
+	cases := []Case{
+		{input: "%F %T ", output: "2024-03-05 07:08:09 ", name: "date and time"},
+		{input: "%s", output: "1709622489", name: "epoch"},
+		{input: "100%% %q", output: "100% %q", name: "literal percent"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := strftime(testCase.input, stamp)
+			if got != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, got)
+			}
+		})
+	}
+}
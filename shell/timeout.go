@@ -0,0 +1,80 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// parseDuration accepts a Go duration ("1.5s", "200ms") or, like coreutils
+// timeout, a number of seconds with an optional s, m, h or d suffix.
+func parseDuration(value string) (time.Duration, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return duration, nil
+	}
+
+	unit := time.Second
+	number := value
+	if len(value) > 0 {
+		switch value[len(value)-1] {
+		case 'm':
+			unit = time.Minute
+		case 'h':
+			unit = time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		}
+		if unit != time.Second || value[len(value)-1] == 's' {
+			number = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("timeout: invalid time interval '%s'", value)
+	}
+
+	return time.Duration(n * float64(unit)), nil
+}
+
+// handleTimeoutCommand gets raw args so that the arguments of the command it
+// runs keep their spacing, the separators are skipped here.
+func (shell *Shell) handleTimeoutCommand(args []string) (string, error) {
+	words := []int{}
+	for index, arg := range args {
+		if arg != " " {
+			words = append(words, index)
+		}
+		if len(words) == 2 {
+			break
+		}
+	}
+
+	if len(words) < 2 {
+		return "", &statusError{status: 125, message: "timeout: usage: timeout DURATION command [arg ...]"}
+	}
+
+	duration, err := parseDuration(args[words[0]])
+	if err != nil {
+		return "", &statusError{status: 125, message: err.Error()}
+	}
+
+	command := Command(args[words[1]])
+	rest := args[words[1]+1:]
+	if len(rest) > 0 && rest[0] == " " {
+		rest = rest[1:]
+	}
+
+	ctx, cancel := context.WithTimeout(shell.Context(), duration)
+	defer cancel()
+
+	output, err := shell.handleCommand(ctx, command, rest)
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, &statusError{status: 124}
+	}
+
+	return output, err
+}
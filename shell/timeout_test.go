@@ -0,0 +1,95 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{input: "2", expected: 2 * time.Second},
+		{input: "1.5", expected: 1500 * time.Millisecond},
+		{input: "300ms", expected: 300 * time.Millisecond},
+		{input: "2m", expected: 2 * time.Minute},
+		{input: "1d", expected: 24 * time.Hour},
+	}
+
+	for _, testCase := range cases {
+		got, err := parseDuration(testCase.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", testCase.input, err)
+		}
+		if got != testCase.expected {
+			t.Errorf("%q: expected %v, got: %v", testCase.input, testCase.expected, got)
+		}
+	}
+
+	if _, err := parseDuration("soon"); err == nil {
+		t.Errorf("Expected invalid duration to fail")
+	}
+}
+
+func TestTimeoutCommand(t *testing.T) {
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"PATH=/usr/bin:/bin"}})
+
+	start := time.Now()
+	status, _ := shell.Eval("timeout 0.2 sh -c 'sleep 10 & sleep 10'")
+
+	if status != 124 {
+		t.Errorf("Expected status 124, got: %d", status)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the process group to be killed, took: %v", elapsed)
+	}
+
+	status, _ = shell.Eval("timeout 5 echo a b")
+	if status != 0 || output.String() != "a b\n" {
+		t.Errorf("Expected command to finish in time, got status %d and %q", status, output.String())
+	}
+
+	output.Reset()
+	shell.Eval("echo piped | timeout 5 cat")
+	if output.String() != "piped\n" {
+		t.Errorf("Expected the command to read the pipeline, got: %q", output.String())
+	}
+}
+
+func TestEvalContextCancelsPipeline(t *testing.T) {
+	var output bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &output, Env: []string{"PATH=/usr/bin:/bin"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	status, _ := shell.EvalContext(ctx, "sleep 10 | sleep 10")
+
+	if status == 0 {
+		t.Errorf("Expected cancelled pipeline to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected pipeline to be cancelled, took: %v", elapsed)
+	}
+}
+
+func TestWaitDoesNotWaitForLeftChildren(t *testing.T) {
+	var output bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &output, Env: []string{"PATH=/usr/bin:/bin"}})
+
+	start := time.Now()
+	status, _ := shell.Eval("sh -c 'sleep 3 & echo started'")
+
+	if status != 0 || output.String() != "started\n" {
+		t.Errorf("Expected the command to succeed, got status %d and %q", status, output.String())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected Wait to stop after waitDelay, took: %v", elapsed)
+	}
+}
@@ -0,0 +1,214 @@
+package shell
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// keywords are reported by type like bash does, the parser itself only knows
+// "{" and "}" as part of function definitions.
+var keywords = []string{"!", "[[", "]]", "case", "coproc", "do", "done", "elif", "else", "esac", "fi", "for", "function", "if", "in", "select", "then", "time", "until", "while", "{", "}"}
+
+const (
+	kindAlias    = "alias"
+	kindKeyword  = "keyword"
+	kindFunction = "function"
+	kindBuiltin  = "builtin"
+	kindFile     = "file"
+)
+
+// commandMatch is one way a name can be run, value is the alias text or the
+// path of the file.
+type commandMatch struct {
+	kind   string
+	value  string
+	hashed bool
+}
+
+// lookupAll returns what name resolves to, in the order the shell tries:
+// alias, keyword, function, builtin and then files. Unless all is set only
+// the first match is returned. pathOnly skips everything but files.
+func (shell *Shell) lookupAll(name string, all bool, pathOnly bool) []commandMatch {
+	matches := []commandMatch{}
+	done := func() bool {
+		return len(matches) > 0 && !all
+	}
+
+	if !pathOnly {
+		if value, ok := shell.aliases[name]; ok {
+			matches = append(matches, commandMatch{kind: kindAlias, value: value})
+		}
+		if !done() && slices.Contains(keywords, name) {
+			matches = append(matches, commandMatch{kind: kindKeyword})
+		}
+		if _, ok := shell.functions[name]; ok && !done() {
+			matches = append(matches, commandMatch{kind: kindFunction})
+		}
+		if !done() && shell.isBuiltinCommand(Command(name)) {
+			matches = append(matches, commandMatch{kind: kindBuiltin})
+		}
+	}
+	if done() {
+		return matches
+	}
+
+	if strings.Contains(name, "/") {
+		if _, err := shell.lookCommand(name); err == nil {
+			matches = append(matches, commandMatch{kind: kindFile, value: name})
+		}
+		return matches
+	}
+
+	if path, ok := shell.hash.peek(name); ok && !all {
+		return append(matches, commandMatch{kind: kindFile, value: path, hashed: true})
+	}
+
+	paths := findAllFiles(shell.workingDirectory(), shell.getenv("PATH"), name)
+	for _, path := range paths {
+		matches = append(matches, commandMatch{kind: kindFile, value: path})
+		if !all {
+			break
+		}
+	}
+
+	return matches
+}
+
+func (shell *Shell) describe(name string, match commandMatch) string {
+	switch match.kind {
+	case kindAlias:
+		return name + " is aliased to `" + match.value + "'"
+	case kindKeyword:
+		return name + " is a shell keyword"
+	case kindFunction:
+		return name + " is a function\n" + name + " () { " + shell.functions[name].Source + " }"
+	case kindBuiltin:
+		return name + " is a shell builtin"
+	}
+
+	if match.hashed {
+		return name + " is hashed (" + match.value + ")"
+	}
+	return name + " is " + match.value
+}
+
+func (shell *Shell) handleTypeCommand(args []string) (string, error) {
+	all, kindOnly, pathOnly, forcePath := false, false, false, false
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && len(args[0]) > 1 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'a':
+				all = true
+			case 't':
+				kindOnly = true
+			case 'p':
+				pathOnly = true
+			case 'P':
+				forcePath = true
+			default:
+				return "", &statusError{status: 2, message: fmt.Sprintf("type: -%c: invalid option\ntype: usage: type [-afptP] name [name ...]", flag)}
+			}
+		}
+		args = args[1:]
+	}
+
+	lines := []string{}
+	missing := []string{}
+	for _, name := range args {
+		matches := shell.lookupAll(name, all, forcePath)
+		if len(matches) == 0 {
+			if !kindOnly && !pathOnly && !forcePath {
+				missing = append(missing, name+": not found")
+			} else {
+				missing = append(missing, "")
+			}
+			continue
+		}
+
+		for _, match := range matches {
+			switch {
+			case kindOnly:
+				lines = append(lines, match.kind)
+			case pathOnly || forcePath:
+				if match.kind == kindFile {
+					lines = append(lines, match.value)
+				}
+			default:
+				lines = append(lines, shell.describe(name, match))
+			}
+		}
+	}
+
+	output := strings.Join(lines, "\n")
+	if len(missing) > 0 {
+		return output, &statusError{status: 1, message: strings.TrimSpace(strings.Join(missing, "\n"))}
+	}
+	return output, nil
+}
+
+// nextWord skips the separators at the start of raw args and returns the
+// first word together with the raw arguments that follow it.
+func nextWord(args []string) (string, []string, bool) {
+	for len(args) > 0 && args[0] == " " {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return "", nil, false
+	}
+
+	rest := args[1:]
+	if len(rest) > 0 && rest[0] == " " {
+		rest = rest[1:]
+	}
+	return args[0], rest, true
+}
+
+// handleCommandCommand gets raw args so that the command it runs keeps the
+// spacing of its own arguments.
+func (shell *Shell) handleCommandCommand(args []string) (string, error) {
+	name, rest, ok := nextWord(args)
+	if !ok {
+		return "", nil
+	}
+
+	if name == "-v" || name == "-V" {
+		lines := []string{}
+		missing := []string{}
+		for _, target := range filterParams(rest) {
+			matches := shell.lookupAll(target, false, false)
+			if len(matches) == 0 {
+				if name == "-V" {
+					missing = append(missing, "command: "+target+": not found")
+				}
+				continue
+			}
+
+			match := matches[0]
+			switch {
+			case name == "-V":
+				lines = append(lines, shell.describe(target, match))
+			case match.kind == kindAlias:
+				lines = append(lines, formatAlias(target, match.value))
+			case match.kind == kindFile:
+				lines = append(lines, match.value)
+			default:
+				lines = append(lines, target)
+			}
+		}
+
+		output := strings.Join(lines, "\n")
+		if len(lines) == 0 || len(missing) > 0 {
+			return output, &statusError{status: 1, message: strings.Join(missing, "\n")}
+		}
+		return output, nil
+	}
+
+	command := Command(name)
+	return shell.runHandler(shell.Context(), command, shell.getBuiltinOrExternal(command), rest)
+}
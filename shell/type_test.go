@@ -0,0 +1,89 @@
+package shell
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTypeFlags(t *testing.T) {
+	directory := t.TempDir()
+	os.Mkdir(filepath.Join(directory, "first"), 0755)
+	os.Mkdir(filepath.Join(directory, "second"), 0755)
+	os.WriteFile(filepath.Join(directory, "first/echo"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(directory, "second/echo"), []byte("#!/bin/sh\n"), 0755)
+	first := filepath.Join(directory, "first/echo")
+	second := filepath.Join(directory, "second/echo")
+
+	cases := []Case{
+		{input: "type echo pwd", output: "echo is a shell builtin\npwd is a shell builtin", name: "several names"},
+		{input: "type -a echo", output: "echo is a shell builtin\necho is " + first + "\necho is " + second, name: "all matches"},
+		{input: "type -t echo ll greet if", output: "builtin\nalias\nfunction\nkeyword", name: "one word kind"},
+		{input: "type -p echo", output: "", err: "", name: "no path for builtin"},
+		{input: "type -P echo", output: first, name: "force path search"},
+		{input: "type -aP echo", output: first + "\n" + second, name: "force path search all"},
+		{input: "type ll", output: "ll is aliased to `ls -l'", name: "alias"},
+		{input: "type if", output: "if is a shell keyword", name: "keyword"},
+		{input: "type greet", output: "greet is a function\ngreet () { echo hi; }", name: "function"},
+		{input: "type nosuch", err: "nosuch: not found", name: "not found"},
+		{input: "type -x echo", err: "type: -x: invalid option\ntype: usage: type [-afptP] name [name ...]", name: "invalid option"},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var output bytes.Buffer
+			var errout bytes.Buffer
+			shell := New(Options{
+				Stdout: &output,
+				Stderr: &errout,
+				Env:    []string{"PATH=" + filepath.Join(directory, "first") + ":" + filepath.Join(directory, "second")},
+			})
+			shell.Eval("alias ll='ls -l'")
+			shell.Eval("greet() { echo hi; }")
+
+			shell.Eval(testCase.input)
+
+			if got := strings.TrimRight(output.String(), "\n"); got != testCase.output {
+				t.Errorf("Expected result to be %q, got: %q", testCase.output, got)
+			}
+			if got := strings.TrimRight(errout.String(), "\n"); got != testCase.err {
+				t.Errorf("Expected error to be %q, got: %q", testCase.err, got)
+			}
+		})
+	}
+}
+
+func TestCommandCommand(t *testing.T) {
+	var output bytes.Buffer
+	var errout bytes.Buffer
+	shell := New(Options{Stdout: &output, Stderr: &errout, Env: []string{"PATH=/usr/bin:/bin"}})
+
+	shell.Eval("echo() { pwd; }")
+	shell.Eval("alias ll='ls -l'")
+
+	cases := []struct {
+		input  string
+		output string
+		status int
+	}{
+		{input: "command echo a b", output: "a b"},
+		{input: "command -v echo ll", output: "echo\nalias ll='ls -l'"},
+		{input: "command -v sh", output: "/usr/bin/sh"},
+		{input: "command -V ll", output: "ll is aliased to `ls -l'"},
+		{input: "command -v nosuch", output: "", status: 1},
+	}
+
+	for _, testCase := range cases {
+		output.Reset()
+		status, _ := shell.Eval(testCase.input)
+
+		if got := strings.TrimRight(output.String(), "\n"); got != testCase.output {
+			t.Errorf("%q: expected result to be %q, got: %q", testCase.input, testCase.output, got)
+		}
+		if status != testCase.status {
+			t.Errorf("%q: expected status %d, got: %d", testCase.input, testCase.status, status)
+		}
+	}
+}